@@ -0,0 +1,180 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/liweiming-nova/common/etcd"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+type ElectionOptions func(plugin *ElectionPlugin)
+
+// ElectionPlugin 基于 etcd 的 leader 选举，只有 leader 副本执行 OnElected 中的任务（定时任务、outbox 投递等）
+//
+// OnElected 收到的 ctx 在失去 leader 身份或插件停止时取消，任务应随之退出；
+// 插件启动后以 "election.<name>" 存入 PluginContext，其他插件可通过 IsLeader 判断
+type ElectionPlugin struct {
+	name      string
+	value     string
+	etcdName  string
+	ttl       int64
+	onElected func(ctx context.Context)
+	onRevoked func()
+
+	election *etcd.Election
+	leader   atomic.Bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// WithElectionValue 参选时写入的值，默认 hostname-pid
+func WithElectionValue(value string) ElectionOptions {
+	return func(plugin *ElectionPlugin) {
+		plugin.value = value
+	}
+}
+
+// WithElectionEtcd 使用 [etcd.<name>] 对应的客户端，默认使用默认客户端
+func WithElectionEtcd(name string) ElectionOptions {
+	return func(plugin *ElectionPlugin) {
+		plugin.etcdName = name
+	}
+}
+
+// WithElectionTTL 租约秒数，leader 异常退出后最多经过该时长完成切换，默认取 etcd.lease_ttl
+func WithElectionTTL(ttl int64) ElectionOptions {
+	return func(plugin *ElectionPlugin) {
+		plugin.ttl = ttl
+	}
+}
+
+// WithOnElected 成为 leader 后在独立 goroutine 中调用
+func WithOnElected(fn func(ctx context.Context)) ElectionOptions {
+	return func(plugin *ElectionPlugin) {
+		plugin.onElected = fn
+	}
+}
+
+// WithOnRevoked 失去 leader 身份后调用，此时 OnElected 的 ctx 已取消
+func WithOnRevoked(fn func()) ElectionOptions {
+	return func(plugin *ElectionPlugin) {
+		plugin.onRevoked = fn
+	}
+}
+
+func NewElectionPlugin(name string, opts ...ElectionOptions) *ElectionPlugin {
+	hostname, _ := os.Hostname()
+	plugin := &ElectionPlugin{
+		name:  name,
+		value: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+	for _, opt := range opts {
+		opt(plugin)
+	}
+	return plugin
+}
+
+func (plugin *ElectionPlugin) BeforeStart(ctx *PluginContext) error {
+	if plugin.name == "" {
+		return fmt.Errorf("election name cannot be empty")
+	}
+	return nil
+}
+
+// Start 后台参选，不阻塞启动流程
+func (plugin *ElectionPlugin) Start(ctx *PluginContext) error {
+	client, err := etcd.Named(plugin.etcdName)
+	if err != nil {
+		return err
+	}
+	ttl := plugin.ttl
+	if ttl <= 0 {
+		ttl = client.LeaseTTL()
+	}
+	plugin.election = etcd.NewElection(client.DefaultClient(), "/election/"+plugin.name, ttl)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	plugin.cancel = cancel
+	plugin.done = make(chan struct{})
+	go plugin.run(runCtx)
+
+	ctx.Set("election."+plugin.name, plugin)
+	return nil
+}
+
+func (plugin *ElectionPlugin) run(ctx context.Context) {
+	defer close(plugin.done)
+	backoff := 500 * time.Millisecond
+	for {
+		if err := plugin.election.Campaign(ctx, plugin.value); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			xlog.Errorf(ctx, "election [%s] campaign error: %v", plugin.name, err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			continue
+		}
+		backoff = 500 * time.Millisecond
+
+		xlog.Infof(ctx, "election [%s] elected as leader: %s", plugin.name, plugin.value)
+		leaderCtx, revoke := context.WithCancel(ctx)
+		plugin.leader.Store(true)
+		if plugin.onElected != nil {
+			plugin.wg.Add(1)
+			go func() {
+				defer plugin.wg.Done()
+				plugin.onElected(leaderCtx)
+			}()
+		}
+
+		select {
+		case <-plugin.election.Done():
+			xlog.Warnf(ctx, "election [%s] leadership lost: %s", plugin.name, plugin.value)
+		case <-ctx.Done():
+		}
+		plugin.leader.Store(false)
+		revoke()
+		plugin.wg.Wait()
+		if plugin.onRevoked != nil {
+			plugin.onRevoked()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// IsLeader 当前副本是否为 leader
+func (plugin *ElectionPlugin) IsLeader() bool {
+	return plugin.leader.Load()
+}
+
+// Election 返回底层选举，可用于查询或观察当前 leader；Start 之前为 nil
+func (plugin *ElectionPlugin) Election() *etcd.Election {
+	return plugin.election
+}
+
+// Stop 停止参选，等待 leader 任务退出后放弃 leader 身份
+func (plugin *ElectionPlugin) Stop() error {
+	if plugin.cancel == nil {
+		return nil
+	}
+	plugin.cancel()
+	<-plugin.done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return plugin.election.Resign(ctx)
+}
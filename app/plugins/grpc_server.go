@@ -1,16 +1,21 @@
 package plugins
 
 import (
+	"context"
+	"errors"
+
 	"github.com/liweiming-nova/common/grpcx/server"
+	"github.com/liweiming-nova/common/xlog"
 	"google.golang.org/grpc"
 )
 
 type Options func(plugins *GRPCPlugins)
 
 type GRPCPlugins struct {
-	interceptor  []grpc.UnaryServerInterceptor //拦截器
-	registerFunc func(*grpc.Server)
-	name         string
+	interceptor       []grpc.UnaryServerInterceptor  //拦截器
+	streamInterceptor []grpc.StreamServerInterceptor //流式拦截器
+	registerFunc      func(*grpc.Server)
+	name              string
 }
 
 func WithInterceptors(interceptors ...grpc.UnaryServerInterceptor) Options {
@@ -19,6 +24,12 @@ func WithInterceptors(interceptors ...grpc.UnaryServerInterceptor) Options {
 	}
 }
 
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Options {
+	return func(plugins *GRPCPlugins) {
+		plugins.streamInterceptor = interceptors
+	}
+}
+
 func WithRegisterFunc(re func(*grpc.Server)) Options {
 	return func(plugins *GRPCPlugins) {
 		plugins.registerFunc = re
@@ -43,11 +54,18 @@ func (plugins *GRPCPlugins) Start(ctx *PluginContext) error {
 	if plugins.registerFunc == nil {
 		panic("grpc registerFunc is nil")
 	}
-	return server.StartServer(plugins.name, plugins.registerFunc, plugins.interceptor)
+	return server.StartServer(plugins.name, plugins.registerFunc, plugins.interceptor,
+		server.WithStreamInterceptors(plugins.streamInterceptor...))
 }
 
+// Stop 优雅停止服务，排空超时被强制中断时记录仍在处理的 RPC 数
 func (plugins *GRPCPlugins) Stop() error {
-	return server.StopServer(plugins.name)
+	err := server.StopServer(plugins.name)
+	var forced *server.ForcedStopError
+	if errors.As(err, &forced) {
+		xlog.Warnf(context.Background(), "gRPC Server [%s] forced to stop with %d rpcs in flight", plugins.name, forced.Inflight)
+	}
+	return err
 }
 
 func (plugins *GRPCPlugins) BeforeStart(ctx *PluginContext) error {
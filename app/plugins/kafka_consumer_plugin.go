@@ -2,14 +2,17 @@ package plugins
 
 import (
 	"context"
+	"fmt"
 	ckafka "github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/liweiming-nova/common/config"
+	"github.com/liweiming-nova/common/utils/metrics"
 	"github.com/liweiming-nova/common/xlog"
 	"github.com/panjf2000/ants/v2"
 	"go.uber.org/zap"
 	"log"
 	"os"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"time"
 )
@@ -137,7 +140,7 @@ func (p *KafkaConsumerPlugin) processMessage(msg *ckafka.Message) {
 			time.Sleep(time.Second)
 		}
 
-		err := p.handler(msg)
+		err := p.invokeHandler(msg)
 		if err == nil {
 			if _, err := p.consumer.CommitMessage(msg); err != nil {
 				xlog.Errorf(p.ctx, "Failed to commit message:%v", zap.Error(err))
@@ -157,6 +160,29 @@ func (p *KafkaConsumerPlugin) processMessage(msg *ckafka.Message) {
 	)
 }
 
+// invokeHandler 调用消息处理函数，panic 转为错误以便按失败重试
+func (p *KafkaConsumerPlugin) invokeHandler(msg *ckafka.Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			topic := ""
+			if msg.TopicPartition.Topic != nil {
+				topic = *msg.TopicPartition.Topic
+			}
+			traceID := ""
+			for _, h := range msg.Headers {
+				if h.Key == xlog.TraceId {
+					traceID = string(h.Value)
+				}
+			}
+			metrics.GetCounter("kafka_consumer_panics_total", "topic", topic).Inc()
+			xlog.Errorf(p.ctx, "Kafka handler panic - topic=%s, partition=%d, offset=%d, TraceId: %s, Panic: %v\n%s",
+				topic, msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset), traceID, r, debug.Stack())
+			err = fmt.Errorf("kafka handler panic: %v", r)
+		}
+	}()
+	return p.handler(msg)
+}
+
 func (p *KafkaConsumerPlugin) Stop() error {
 	p.consumer.Close()
 
@@ -1,10 +1,14 @@
 package plugins
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/liweiming-nova/common/config"
 	"github.com/liweiming-nova/common/config/options"
+	"github.com/liweiming-nova/common/utils"
+	"github.com/liweiming-nova/common/xlog"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -29,12 +33,21 @@ type Cfg struct {
 	PoolMaxOpenConn     int           `toml:"max_open_conn"`     // 最大连接数大小
 	PoolMaxIdleConn     int           `toml:"max_idle_conn"`     // 最大空闲的连接的个数
 	PoolConnMaxLifetime time.Duration `toml:"conn_max_lifetime"` // 连接的生命时间,超过此时间，连接将关闭后重新建立新的，0代表忽略相关判断,单位:second
+
+	// reload
+	CloseDelay time.Duration `toml:"close_delay"` // 配置变更后旧连接池延迟关闭的时间，如 "30s"，默认 30s
 }
 
+// defaultCloseDelay close_delay 未配置时旧连接池延迟关闭的时间
+const defaultCloseDelay = 30 * time.Second
+
 var (
-	once  sync.Once
-	lock  sync.RWMutex
-	pools map[string]*gorm.DB
+	once       sync.Once
+	lock       sync.RWMutex
+	pools      map[string]*gorm.DB
+	poolCfgs   map[string]*Cfg // 连接池对应的配置，用于配置变更时判断是否需要重建
+	// poolsGen 每次配置变更时递增，用于识别按旧配置创建的连接池
+	poolsGen uint64
 )
 
 type sqlConfig struct {
@@ -43,6 +56,13 @@ type sqlConfig struct {
 
 func init() {
 	pools = map[string]*gorm.DB{}
+	poolCfgs = map[string]*Cfg{}
+}
+
+// hash 计算配置摘要
+func (c *Cfg) hash() string {
+	b, _ := json.Marshal(c)
+	return utils.Md5String(string(b))
 }
 
 // Valid 参数names是实例的名称列表，如果为空则检测所有配置的实例
@@ -92,17 +112,38 @@ func getPool(name string) (r *gorm.DB, err error) {
 	return
 }
 
+// addPool 在锁外建立连接池，写入时以先写入者为准，落选的连接池直接关闭；
+// 建立期间配置发生变更时丢弃按旧配置建立的连接池并重试
 func addPool(name string) (r *gorm.DB, err error) {
-	var cfg *Cfg
-	if cfg, err = loadCfg(name); err != nil {
-		return
-	}
-	r = NewSqlPool(cfg)
+	for {
+		lock.RLock()
+		gen := poolsGen
+		lock.RUnlock()
 
-	lock.Lock()
-	pools[name] = r
-	lock.Unlock()
-	return
+		var cfg *Cfg
+		if cfg, err = loadCfg(name); err != nil {
+			return
+		}
+		// NewSqlPool 会修改 Engine，先保留原始配置用于变更比较
+		snapshot := *cfg
+		orm := NewSqlPool(cfg)
+
+		lock.Lock()
+		if r = pools[name]; r != nil {
+			lock.Unlock()
+			closeSqlPool(name, orm)
+			return
+		}
+		if gen != poolsGen {
+			lock.Unlock()
+			closeSqlPool(name, orm)
+			continue
+		}
+		pools[name] = orm
+		poolCfgs[name] = &snapshot
+		lock.Unlock()
+		return orm, nil
+	}
 }
 
 func loadCfg(name string) (r *Cfg, err error) {
@@ -122,9 +163,11 @@ func loadCfgs() (r map[string]*Cfg, err error) {
 
 	once.Do(func() {
 		config.Get(&sqlConfig{}, options.WithOpOnChangeFn(func(cfg interface{}) {
-			lock.Lock()
-			defer lock.Unlock()
-			pools = map[string]*gorm.DB{}
+			var cfgs map[string]*Cfg
+			if c, ok := cfg.(*sqlConfig); ok {
+				cfgs = c.Cfgs
+			}
+			reloadPools(cfgs)
 		}))
 	})
 
@@ -141,6 +184,43 @@ func loadCfgs() (r map[string]*Cfg, err error) {
 	return
 }
 
+// reloadPools 仅重建配置发生变化的连接池，已删除的配置对应连接池同样移除；
+// 旧连接池在其 close_delay 之后关闭，仍持有旧句柄的请求可以继续执行完
+func reloadPools(cfgs map[string]*Cfg) {
+	lock.Lock()
+	defer lock.Unlock()
+	poolsGen++
+	for name, orm := range pools {
+		old := poolCfgs[name]
+		if c := cfgs[name]; c != nil && old != nil && c.hash() == old.hash() {
+			continue
+		}
+		delete(pools, name)
+		delete(poolCfgs, name)
+		delay := defaultCloseDelay
+		if old != nil && old.CloseDelay > 0 {
+			delay = old.CloseDelay
+		}
+		time.AfterFunc(delay, func() { closeSqlPool(name, orm) })
+	}
+}
+
+// closeSqlPool 关闭旧连接池，sql.DB.Close 会等待已开始的查询执行完毕
+func closeSqlPool(name string, orm *gorm.DB) {
+	if orm == nil {
+		return
+	}
+	db, err := orm.DB()
+	if err == nil {
+		err = db.Close()
+	}
+	if err != nil {
+		xlog.Errorf(context.Background(), "Close old sql pool %s error: %v", name, err)
+		return
+	}
+	xlog.Infof(context.Background(), "Closed old sql pool: %s", name)
+}
+
 func NewSqlPool(cfg *Cfg) *gorm.DB {
 	gcfg := &gorm.Config{}
 	if cfg.Debug == true {
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// stubDriver 不连接数据库的 sql 驱动，仅用于验证连接池的开关
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("plugins_stub", stubDriver{})
+}
+
+func newStubPool(t *testing.T) (*gorm.DB, *sql.DB) {
+	db, err := sql.Open("plugins_stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orm, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orm, db
+}
+
+func TestReloadPoolsClosesAfterDelay(t *testing.T) {
+	kept, keptDB := newStubPool(t)
+	changed, changedDB := newStubPool(t)
+	keptCfg := &Cfg{Engine: "mysql", DialHost: "kept"}
+	lock.Lock()
+	pools["kept"], poolCfgs["kept"] = kept, keptCfg
+	pools["changed"], poolCfgs["changed"] = changed, &Cfg{Engine: "mysql", DialHost: "old", CloseDelay: 50 * time.Millisecond}
+	lock.Unlock()
+	defer func() {
+		lock.Lock()
+		delete(pools, "kept")
+		delete(poolCfgs, "kept")
+		lock.Unlock()
+		keptDB.Close()
+	}()
+
+	reloadPools(map[string]*Cfg{"kept": keptCfg, "changed": {Engine: "mysql", DialHost: "new"}})
+
+	lock.RLock()
+	_, hasKept := pools["kept"]
+	_, hasChanged := pools["changed"]
+	lock.RUnlock()
+	if !hasKept || hasChanged {
+		t.Fatalf("only the changed pool should be removed, kept=%v changed=%v", hasKept, hasChanged)
+	}
+
+	// 仍持有旧句柄的请求在延迟期内可以继续使用
+	if err := changedDB.Ping(); err != nil {
+		t.Fatalf("old pool should stay open during the grace period: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := changedDB.Ping(); err == nil {
+		t.Fatal("old pool should be closed after the grace period")
+	}
+	if err := keptDB.Ping(); err != nil {
+		t.Fatalf("unchanged pool should stay open: %v", err)
+	}
+}
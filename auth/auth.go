@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 凭证类型
+const (
+	TypeJWT    = "jwt"
+	TypeAPIKey = "api_key"
+	TypeHMAC   = "hmac"
+)
+
+// 凭证使用的请求头（gRPC 中为小写 metadata key）
+const (
+	HeaderAuthorization = "authorization"
+	HeaderAPIKey        = "x-api-key"
+	HeaderKeyID         = "x-auth-key-id"
+	HeaderTimestamp     = "x-auth-timestamp"
+	HeaderNonce         = "x-auth-nonce"
+	HeaderSignature     = "x-auth-signature"
+)
+
+var (
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Cfg 服务端认证配置，对应 [rpc.server.<name>.auth] 与 [rest.server.<name>.auth]
+type Cfg struct {
+	Enable bool       `toml:"enable"`
+	JWT    *JWTCfg    `toml:"jwt"`
+	APIKey *APIKeyCfg `toml:"api_key"`
+	HMAC   *HMACCfg   `toml:"hmac"`
+	Rules  []*Rule    `toml:"rules"` // 按顺序匹配，首个命中的规则生效；未命中时要求任意合法身份
+}
+
+// Enabled 判断是否启用认证
+func (c *Cfg) Enabled() bool {
+	return c != nil && c.Enable
+}
+
+// APIKeyCfg 静态 API Key 配置
+type APIKeyCfg struct {
+	Enable bool              `toml:"enable"`
+	Header string            `toml:"header"` // 默认 x-api-key
+	Keys   map[string]string `toml:"keys"`   // key -> subject
+}
+
+// HMACCfg HMAC 签名配置
+type HMACCfg struct {
+	Enable       bool              `toml:"enable"`
+	Keys         map[string]string `toml:"keys"`           // key id -> secret
+	MaxSkew      time.Duration     `toml:"max_skew"`       // 允许的时间戳偏差，默认 5m；偏差范围内同一签名只能使用一次
+	MaxBodyBytes int64             `toml:"max_body_bytes"` // HTTP 请求体参与签名时允许的最大字节数，默认 10MB
+}
+
+func (c *HMACCfg) maxSkew() time.Duration {
+	if c.MaxSkew <= 0 {
+		return 5 * time.Minute
+	}
+	return c.MaxSkew
+}
+
+// Rule 方法级访问规则
+// Method 为 gRPC 完整方法名或 HTTP 路径，以 * 结尾时按前缀匹配；
+// Allow/Deny 中的条目为 subject、role:<角色> 或 *
+type Rule struct {
+	Method string   `toml:"method"`
+	Public bool     `toml:"public"` // 无需认证
+	Allow  []string `toml:"allow"`  // 非空时仅允许匹配的身份
+	Deny   []string `toml:"deny"`
+}
+
+func (r *Rule) match(method string) bool {
+	if strings.HasSuffix(r.Method, "*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(r.Method, "*"))
+	}
+	return r.Method == method
+}
+
+// Principal 调用方身份
+type Principal struct {
+	Type    string                 // jwt / api_key / hmac
+	Subject string                 // JWT sub、API Key 对应的 subject 或 HMAC key id
+	Roles   []string               // JWT 中的 roles 或 scope
+	Claims  map[string]interface{} // JWT 声明，其他类型为空
+}
+
+// HasRole 判断是否具有指定角色
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) matchAny(entries []string) bool {
+	for _, e := range entries {
+		if e == "*" || e == p.Subject || (strings.HasPrefix(e, "role:") && p.HasRole(strings.TrimPrefix(e, "role:"))) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext 将身份写入 context
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext 从 context 中获取身份
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Authenticator 按配置校验请求凭证并执行访问规则
+type Authenticator struct {
+	cfg    *Cfg
+	jwt    *jwtVerifier
+	replay *replayCache
+}
+
+// New 创建认证器
+func New(cfg *Cfg) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg}
+	if a.hmacEnabled() {
+		a.replay = newReplayCache(2 * cfg.HMAC.maxSkew())
+	}
+	if cfg.JWT != nil && cfg.JWT.Enable {
+		v, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	if a.jwt == nil && !a.apiKeyEnabled() && !a.hmacEnabled() {
+		return nil, errors.New("auth enabled but no jwt, api_key or hmac configured")
+	}
+	return a, nil
+}
+
+func (a *Authenticator) apiKeyEnabled() bool {
+	return a.cfg.APIKey != nil && a.cfg.APIKey.Enable
+}
+
+func (a *Authenticator) hmacEnabled() bool {
+	return a.cfg.HMAC != nil && a.cfg.HMAC.Enable
+}
+
+// Request 待认证的请求
+type Request struct {
+	Method string                  // 规则匹配使用的 gRPC 完整方法名或 HTTP 路径
+	Target string                  // HMAC 签名目标，gRPC 完整方法名或 HTTPTarget 的结果
+	Header func(key string) string // 按名称读取请求头
+	Body   func() ([]byte, error)  // HMAC 签名使用的请求体，仅在使用 HMAC 时读取，nil 表示空
+}
+
+// Authenticate 校验请求并返回调用方身份
+func (a *Authenticator) Authenticate(req *Request) (*Principal, error) {
+	method := req.Method
+	rule := a.rule(method)
+	p, err := a.principal(req)
+	if rule != nil && rule.Public {
+		// 公开方法忽略凭证错误，凭证有效时仍透传身份
+		if err != nil {
+			p = nil
+		}
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%w: missing credentials", ErrUnauthenticated)
+	}
+	if rule != nil {
+		if p.matchAny(rule.Deny) || (len(rule.Allow) > 0 && !p.matchAny(rule.Allow)) {
+			return nil, fmt.Errorf("%w: %s is not allowed to call %s", ErrPermissionDenied, p.Subject, method)
+		}
+	}
+	return p, nil
+}
+
+func (a *Authenticator) rule(method string) *Rule {
+	for _, r := range a.cfg.Rules {
+		if r.match(method) {
+			return r
+		}
+	}
+	return nil
+}
+
+// principal 依次尝试 JWT、HMAC、API Key，未携带任何凭证时返回 nil, nil
+func (a *Authenticator) principal(req *Request) (*Principal, error) {
+	get := req.Header
+	if a.jwt != nil {
+		if token := bearerToken(get(HeaderAuthorization)); token != "" {
+			claims, err := a.jwt.verify(token)
+			if err != nil {
+				return nil, err
+			}
+			return principalFromClaims(claims), nil
+		}
+	}
+	if a.hmacEnabled() {
+		if keyID := get(HeaderKeyID); keyID != "" {
+			return a.verifyHMAC(req)
+		}
+	}
+	if a.apiKeyEnabled() {
+		header := a.cfg.APIKey.Header
+		if header == "" {
+			header = HeaderAPIKey
+		}
+		if key := get(header); key != "" {
+			for k, subject := range a.cfg.APIKey.Keys {
+				if hmac.Equal([]byte(k), []byte(key)) {
+					return &Principal{Type: TypeAPIKey, Subject: subject}, nil
+				}
+			}
+			return nil, errors.New("invalid api key")
+		}
+	}
+	return nil, nil
+}
+
+func (a *Authenticator) verifyHMAC(req *Request) (*Principal, error) {
+	keyID, timestamp, nonce, signature := req.Header(HeaderKeyID), req.Header(HeaderTimestamp), req.Header(HeaderNonce), req.Header(HeaderSignature)
+	secret, ok := a.cfg.HMAC.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown hmac key id %s", keyID)
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid hmac timestamp")
+	}
+	if nonce == "" {
+		return nil, errors.New("missing hmac nonce")
+	}
+	skew := a.cfg.HMAC.maxSkew()
+	if d := time.Since(time.Unix(ts, 0)); d > skew || d < -skew {
+		return nil, errors.New("hmac timestamp out of range")
+	}
+	var body []byte
+	if req.Body != nil {
+		if body, err = req.Body(); err != nil {
+			return nil, fmt.Errorf("read body for hmac: %w", err)
+		}
+	}
+	if !hmac.Equal([]byte(Sign(secret, keyID, timestamp, nonce, req.Target, body)), []byte(signature)) {
+		return nil, errors.New("invalid hmac signature")
+	}
+	// 校验通过后才记录，未持有密钥的调用方无法填充缓存
+	if !a.replay.add(keyID + "\n" + timestamp + "\n" + signature) {
+		return nil, errors.New("hmac signature already used")
+	}
+	return &Principal{Type: TypeHMAC, Subject: keyID}, nil
+}
+
+// Sign 计算 HMAC 签名：
+// hex(HMAC-SHA256(secret, keyID + "\n" + timestamp + "\n" + nonce + "\n" + target + "\n" + hex(SHA256(body))))
+// nonce 为每次请求随机生成的字符串，保证重试的请求签名不同
+func Sign(secret, keyID, timestamp, nonce, target string, body []byte) string {
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(keyID + "\n" + timestamp + "\n" + nonce + "\n" + target + "\n" + hex.EncodeToString(digest[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HTTPTarget 返回 HTTP 请求的签名目标："METHOD path"，带查询参数时为 "METHOD path?query"（原样使用，不重新排序）
+func HTTPTarget(r *http.Request) string {
+	target := r.Method + " " + r.URL.Path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	return target
+}
+
+// replayCache 记录有效期内已使用过的签名
+type replayCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // key -> 过期时间
+	lastPurge time.Time
+}
+
+func newReplayCache(ttl time.Duration) *replayCache {
+	return &replayCache{ttl: ttl, seen: map[string]time.Time{}, lastPurge: time.Now()}
+}
+
+// add 记录签名，已存在且未过期时返回 false
+func (c *replayCache) add(key string) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastPurge) >= c.ttl/2 {
+		for k, expire := range c.seen {
+			if now.After(expire) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPurge = now
+	}
+	if expire, ok := c.seen[key]; ok && now.Before(expire) {
+		return false
+	}
+	c.seen[key] = now.Add(c.ttl)
+	return true
+}
+
+func bearerToken(header string) string {
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func principalFromClaims(claims map[string]interface{}) *Principal {
+	p := &Principal{Type: TypeJWT, Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range roles {
+			if s, ok := r.(string); ok {
+				p.Roles = append(p.Roles, s)
+			}
+		}
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Roles = append(p.Roles, strings.Fields(scope)...)
+	}
+	return p
+}
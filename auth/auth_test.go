@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func headers(kv ...string) func(string) string {
+	m := map[string]string{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		m[kv[i]] = kv[i+1]
+	}
+	return func(key string) string { return m[key] }
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := New(&Cfg{
+		Enable: true,
+		JWT:    &JWTCfg{Enable: true, Secret: "s3cret", Issuer: "idp"},
+		APIKey: &APIKeyCfg{Enable: true, Keys: map[string]string{"k1": "order_server"}},
+		HMAC:   &HMACCfg{Enable: true, Keys: map[string]string{"pay": "hmac-secret"}},
+		Rules: []*Rule{
+			{Method: "/user.UserServer/Ping", Public: true},
+			{Method: "/user.UserServer/Admin*", Allow: []string{"role:admin"}},
+			{Method: "/user.UserServer/*", Deny: []string{"blocked"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin, _ := SignHS("s3cret", map[string]interface{}{"sub": "alice", "iss": "idp", "roles": []string{"admin"}, "exp": time.Now().Add(time.Minute).Unix()})
+	expired, _ := SignHS("s3cret", map[string]interface{}{"sub": "alice", "iss": "idp", "exp": time.Now().Add(-time.Minute).Unix()})
+	forged, _ := SignHS("other", map[string]interface{}{"sub": "alice", "iss": "idp"})
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	cases := []struct {
+		name    string
+		method  string
+		get     func(string) string
+		subject string
+		err     error
+	}{
+		{"public", "/user.UserServer/Ping", headers(), "", nil},
+		{"missing", "/user.UserServer/Get", headers(), "", ErrUnauthenticated},
+		{"jwt", "/user.UserServer/AdminDelete", headers(HeaderAuthorization, "Bearer "+admin), "alice", nil},
+		{"expired", "/user.UserServer/Get", headers(HeaderAuthorization, "Bearer "+expired), "", ErrUnauthenticated},
+		{"forged", "/user.UserServer/Get", headers(HeaderAuthorization, "Bearer "+forged), "", ErrUnauthenticated},
+		{"api key", "/user.UserServer/Get", headers(HeaderAPIKey, "k1"), "order_server", nil},
+		{"api key no role", "/user.UserServer/AdminDelete", headers(HeaderAPIKey, "k1"), "", ErrPermissionDenied},
+		{"hmac", "/user.UserServer/Get", headers(HeaderKeyID, "pay", HeaderTimestamp, ts, HeaderNonce, "n1",
+			HeaderSignature, Sign("hmac-secret", "pay", ts, "n1", "/user.UserServer/Get", nil)), "pay", nil},
+		{"hmac replay", "/user.UserServer/Get", headers(HeaderKeyID, "pay", HeaderTimestamp, ts, HeaderNonce, "n1",
+			HeaderSignature, Sign("hmac-secret", "pay", ts, "n1", "/user.UserServer/Get", nil)), "", ErrUnauthenticated},
+		{"hmac wrong target", "/user.UserServer/Get", headers(HeaderKeyID, "pay", HeaderTimestamp, ts, HeaderNonce, "n2",
+			HeaderSignature, Sign("hmac-secret", "pay", ts, "n2", "/user.UserServer/Other", nil)), "", ErrUnauthenticated},
+		{"hmac tampered body", "/user.UserServer/Get", headers(HeaderKeyID, "pay", HeaderTimestamp, ts, HeaderNonce, "n3",
+			HeaderSignature, Sign("hmac-secret", "pay", ts, "n3", "/user.UserServer/Get", []byte("amount=1"))), "", ErrUnauthenticated},
+	}
+	for _, c := range cases {
+		p, err := a.Authenticate(&Request{Method: c.method, Target: c.method, Header: c.get})
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if c.subject != "" && (p == nil || p.Subject != c.subject) {
+			t.Errorf("%s: unexpected principal %+v", c.name, p)
+		}
+	}
+}
+
+func TestMiddlewareHMAC(t *testing.T) {
+	a, err := New(&Cfg{Enable: true, HMAC: &HMACCfg{Enable: true, Keys: map[string]string{"pay": "hmac-secret"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := func(nonce, target, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/pay?order=1", strings.NewReader(body))
+		r.Header.Set(HeaderKeyID, "pay")
+		r.Header.Set(HeaderTimestamp, ts)
+		r.Header.Set(HeaderNonce, nonce)
+		r.Header.Set(HeaderSignature, Sign("hmac-secret", "pay", ts, nonce, target, []byte(body)))
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signed("n1", "POST /pay?order=1", `{"amount":1}`))
+	if w.Code != http.StatusOK || got != `{"amount":1}` {
+		t.Fatalf("signed request should pass with body intact, code=%d body=%q", w.Code, got)
+	}
+
+	// 重放、篡改请求体、篡改查询参数均应被拒绝
+	replay := signed("n1", "POST /pay?order=1", `{"amount":1}`)
+	tampered := signed("n2", "POST /pay?order=1", `{"amount":1}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"amount":100}`))
+	query := signed("n3", "POST /pay?order=2", `{"amount":1}`)
+	for name, r := range map[string]*http.Request{"replay": replay, "body": tampered, "query": query} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, w.Code)
+		}
+	}
+}
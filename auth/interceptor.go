@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// defaultMaxBodyBytes HTTP 请求体参与 HMAC 签名时默认允许的最大字节数
+const defaultMaxBodyBytes = 10 << 20
+
+// UnaryServerInterceptor 校验 gRPC unary 请求凭证，并将 Principal 写入 context；HMAC 签名覆盖请求消息
+func (a *Authenticator) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.grpcContext(ctx, info.FullMethod, func() ([]byte, error) { return messageBytes(req) })
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor 校验 gRPC stream 请求凭证，并将 Principal 写入 context；HMAC 签名不覆盖流中的消息
+func (a *Authenticator) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.grpcContext(ss.Context(), info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalServerStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *Authenticator) grpcContext(ctx context.Context, method string, body func() ([]byte, error)) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	p, err := a.Authenticate(&Request{Method: method, Target: method, Header: get, Body: body})
+	if err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if p != nil {
+		ctx = NewContext(ctx, p)
+	}
+	return ctx, nil
+}
+
+// messageBytes 以确定性编码序列化请求消息，供 HMAC 计算摘要
+func messageBytes(msg interface{}) ([]byte, error) {
+	m, ok := msg.(proto.Message)
+	if !ok || m == nil {
+		return nil, nil
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
+
+type principalServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// Middleware 校验 HTTP 请求凭证，失败时返回 401/403；HMAC 签名目标见 HTTPTarget，并覆盖请求体
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := func() ([]byte, error) {
+			if r.Body == nil {
+				return nil, nil
+			}
+			limit := int64(defaultMaxBodyBytes)
+			if n := a.cfg.HMAC.MaxBodyBytes; n > 0 {
+				limit = n
+			}
+			b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+			r.Body.Close()
+			// 读取后放回，后续 handler 仍可读取完整请求体
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			return b, err
+		}
+		p, err := a.Authenticate(&Request{Method: r.URL.Path, Target: HTTPTarget(r), Header: r.Header.Get, Body: body})
+		if err != nil {
+			code := http.StatusUnauthorized
+			if errors.Is(err, ErrPermissionDenied) {
+				code = http.StatusForbidden
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		if p != nil {
+			r = r.WithContext(NewContext(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientCfg 客户端凭证配置，对应 [rpc.client.<name>.auth]
+type ClientCfg struct {
+	Type   string `toml:"type"`    // jwt / api_key / hmac
+	Token  string `toml:"token"`   // jwt
+	APIKey string `toml:"api_key"` // api_key
+	Header string `toml:"header"`  // api_key 使用的请求头，默认 x-api-key
+	KeyID  string `toml:"key_id"`  // hmac
+	Secret string `toml:"secret"`  // hmac
+}
+
+// ClientInterceptor 为出站调用附加凭证
+type ClientInterceptor struct {
+	cfg *ClientCfg
+}
+
+// NewClientInterceptor 创建客户端凭证拦截器
+func NewClientInterceptor(cfg *ClientCfg) *ClientInterceptor {
+	return &ClientInterceptor{cfg: cfg}
+}
+
+func (i *ClientInterceptor) outgoing(ctx context.Context, method string, req interface{}) context.Context {
+	switch i.cfg.Type {
+	case TypeJWT:
+		return metadata.AppendToOutgoingContext(ctx, HeaderAuthorization, "Bearer "+i.cfg.Token)
+	case TypeAPIKey:
+		header := i.cfg.Header
+		if header == "" {
+			header = HeaderAPIKey
+		}
+		return metadata.AppendToOutgoingContext(ctx, header, i.cfg.APIKey)
+	case TypeHMAC:
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := newNonce()
+		body, _ := messageBytes(req)
+		return metadata.AppendToOutgoingContext(ctx,
+			HeaderKeyID, i.cfg.KeyID,
+			HeaderTimestamp, ts,
+			HeaderNonce, nonce,
+			HeaderSignature, Sign(i.cfg.Secret, i.cfg.KeyID, ts, nonce, method, body))
+	}
+	return ctx
+}
+
+func (i *ClientInterceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(i.outgoing(ctx, method, req), method, req, reply, cc, opts...)
+}
+
+func (i *ClientInterceptor) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(i.outgoing(ctx, method, nil), desc, cc, method, opts...)
+}
+
+// newNonce 生成随机 nonce
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
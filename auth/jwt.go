@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWTCfg JWT 校验配置，对应 [auth.jwt]
+type JWTCfg struct {
+	Enable        bool          `toml:"enable"`
+	Secret        string        `toml:"secret"`          // HS256/HS384/HS512 密钥
+	PublicKeyFile string        `toml:"public_key_file"` // RS256/RS384/RS512 公钥（PEM，PKIX 公钥或证书）
+	JWKSFile      string        `toml:"jwks_file"`       // JWKS 文件，按 kid 选择 RSA 公钥
+	Issuer        string        `toml:"issuer"`          // 非空时校验 iss
+	Audience      string        `toml:"audience"`        // 非空时校验 aud
+	Leeway        time.Duration `toml:"leeway"`          // exp/nbf 允许的时钟偏差
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+type jwtVerifier struct {
+	cfg       *JWTCfg
+	secret    []byte
+	publicKey *rsa.PublicKey
+	jwks      map[string]*rsa.PublicKey // kid -> key
+}
+
+func newJWTVerifier(cfg *JWTCfg) (*jwtVerifier, error) {
+	v := &jwtVerifier{cfg: cfg, secret: []byte(cfg.Secret)}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key: %w", err)
+		}
+		if v.publicKey, err = parseRSAPublicKey(data); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.JWKSFile != "" {
+		data, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwks file: %w", err)
+		}
+		if v.jwks, err = parseJWKS(data); err != nil {
+			return nil, err
+		}
+	}
+	if len(v.secret) == 0 && v.publicKey == nil && len(v.jwks) == 0 {
+		return nil, errors.New("jwt requires secret, public_key_file or jwks_file")
+	}
+	return v, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt public key is not PEM encoded")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt certificate: %w", err)
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("jwt certificate does not contain an RSA public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		if rsaKey, err2 := x509.ParsePKCS1PublicKey(block.Bytes); err2 == nil {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("parse jwt public key: %w", err)
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("jwt public key is not an RSA key")
+}
+
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	r := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks key %s: %w", k.Kid, err)
+		}
+		r[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return r, nil
+}
+
+func hashFor(alg string) (crypto.Hash, func() hash.Hash, bool) {
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, sha256.New, true
+	case "384":
+		return crypto.SHA384, sha512.New384, true
+	case "512":
+		return crypto.SHA512, sha512.New, true
+	}
+	return 0, nil, false
+}
+
+// verify 校验签名与标准声明，返回全部声明
+func (v *jwtVerifier) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(header.Alg) != 5 {
+		return nil, errInvalidToken
+	}
+	cryptoHash, newHash, ok := hashFor(header.Alg)
+	if !ok {
+		return nil, fmt.Errorf("unsupported jwt alg %s", header.Alg)
+	}
+	signingInput := parts[0] + "." + parts[1]
+
+	switch header.Alg[:2] {
+	case "HS":
+		if len(v.secret) == 0 {
+			return nil, fmt.Errorf("jwt alg %s not configured", header.Alg)
+		}
+		mac := hmac.New(newHash, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return nil, errInvalidToken
+		}
+	case "RS":
+		key := v.publicKey
+		if k, ok := v.jwks[header.Kid]; ok {
+			key = k
+		}
+		if key == nil {
+			return nil, fmt.Errorf("no public key for jwt kid %q", header.Kid)
+		}
+		h := newHash()
+		h.Write([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(key, cryptoHash, h.Sum(nil), sig); err != nil {
+			return nil, errInvalidToken
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg %s", header.Alg)
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errInvalidToken
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *jwtVerifier) validateClaims(claims map[string]interface{}) error {
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(v.cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return errors.New("token issuer mismatch")
+	}
+	if v.cfg.Audience != "" && !containsAudience(claims["aud"], v.cfg.Audience) {
+		return errors.New("token audience mismatch")
+	}
+	return nil
+}
+
+func containsAudience(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, a := range v {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// SignHS 使用 HS256 签发 token，供客户端或测试使用
+func SignHS(secret string, claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -4,12 +4,21 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/liweiming-nova/common/config/options"
+	"reflect"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 	"os"
 	"path/filepath"
 )
 
+// MapUnmarshaler 由类型自行解析配置表，用于顶层字段与子表混用等无法直接映射到结构体的配置
+type MapUnmarshaler interface {
+	UnmarshalMap(data map[string]interface{}) error
+}
+
+var mapUnmarshalerType = reflect.TypeOf((*MapUnmarshaler)(nil)).Elem()
+
 // ViperParser 支持 JSON/YAML/TOML 的通用配置解析器
 type ViperParser struct {
 	modTime int64
@@ -36,13 +45,38 @@ func (p *ViperParser) Unmarshal(cfg interface{}, opts *options.Options) error {
 	}
 
 	// 反序列化到结构体
-	if err := v.Unmarshal(cfg); err != nil {
+	if err := v.Unmarshal(cfg, withMapUnmarshaler); err != nil {
 		return fmt.Errorf("failed to unmarshal into struct: %w", err)
 	}
 
 	return nil
 }
 
+// withMapUnmarshaler 在 viper 默认的解码钩子之前，将表交给实现了 MapUnmarshaler 的类型处理
+func withMapUnmarshaler(c *mapstructure.DecoderConfig) {
+	c.DecodeHook = mapstructure.ComposeDecodeHookFunc(mapUnmarshalerHook, c.DecodeHook)
+}
+
+func mapUnmarshalerHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+	var out reflect.Value
+	switch {
+	case to.Kind() == reflect.Ptr && to.Implements(mapUnmarshalerType):
+		out = reflect.New(to.Elem())
+	case reflect.PointerTo(to).Implements(mapUnmarshalerType):
+		out = reflect.New(to)
+	default:
+		return data, nil
+	}
+	if err := out.Interface().(MapUnmarshaler).UnmarshalMap(table); err != nil {
+		return nil, err
+	}
+	return out.Interface(), nil
+}
+
 // GetLastModTime 返回所有配置文件中最新的修改时间
 func (p *ViperParser) GetLastModTime(opts *options.Options) (int64, error) {
 	sources, err := p.parseSource(opts)
@@ -133,6 +167,7 @@ func (p *ViperParser) mergeConfig(v *viper.Viper, source string) error {
 		return fmt.Errorf("unsupported config type: %s", ext)
 	}
 
+	v.SetConfigType(configType)
 	if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
 		return fmt.Errorf("merge failed: %w", err)
 	}
@@ -4,73 +4,192 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
-	"github.com/liweiming-nova/common/config"
-	clientv3 "go.etcd.io/etcd/client/v3"
-	"io/ioutil"
+	"os"
 	"sync"
 	"time"
 
-	"go.uber.org/zap" // 推荐日志库，可替换为 log.Println
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/liweiming-nova/common/config"
+	"github.com/liweiming-nova/common/xlog"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
+// DefaultName 默认客户端名称，对应 [etcd] 下的顶层配置
+const DefaultName = "default"
+
+// Cfg 单个 etcd 集群的客户端配置
+//
+// 时长字段支持 "5s"、"1m" 等字符串，整数按纳秒处理
+type Cfg struct {
+	Endpoints        []string      `toml:"endpoints" yaml:"endpoints" mapstructure:"endpoints"`
+	DialTimeout      time.Duration `toml:"dial_timeout" yaml:"dial_timeout" mapstructure:"dial_timeout"` // 建立连接超时，默认 5s
+	Username         string        `toml:"username" yaml:"username" mapstructure:"username"`
+	Password         string        `toml:"password" yaml:"password" mapstructure:"password"`
+	LeaseTTL         int64         `toml:"lease_ttl" yaml:"lease_ttl" mapstructure:"lease_ttl"`                            // 服务注册租约 TTL（秒），默认 15
+	AutoSyncInterval time.Duration `toml:"auto_sync_interval" yaml:"auto_sync_interval" mapstructure:"auto_sync_interval"` // 定期从集群同步成员列表，默认不同步
+	KeepaliveTime    time.Duration `toml:"keepalive_time" yaml:"keepalive_time" mapstructure:"keepalive_time"`             // 连接空闲多久后 ping 服务端，默认不开启
+	KeepaliveTimeout time.Duration `toml:"keepalive_timeout" yaml:"keepalive_timeout" mapstructure:"keepalive_timeout"`    // ping 超时时间，默认 20s
+	ProbeTimeout     time.Duration `toml:"probe_timeout" yaml:"probe_timeout" mapstructure:"probe_timeout"`                // 健康探测时每个节点的超时，默认 3s
+
+	TLS struct {
+		CertFile string `toml:"cert_file" yaml:"cert_file" mapstructure:"cert_file"`
+		KeyFile  string `toml:"key_file" yaml:"key_file" mapstructure:"key_file"`
+		CAFile   string `toml:"ca_file" yaml:"ca_file" mapstructure:"ca_file"`
+	} `toml:"tls" yaml:"tls" mapstructure:"tls"`
+}
+
+// Cfgs [etcd] 配置：顶层字段为默认客户端，[etcd.<name>] 子表为命名客户端
+type Cfgs struct {
+	Default *Cfg
+	Named   map[string]*Cfg
+}
+
+// Config 配置文件中的 etcd 部分
 type Config struct {
-	ETCD *struct {
-		Endpoints   []string      `toml:"endpoints" yaml:"endpoints" mapstructure:"endpoints"`
-		DialTimeout time.Duration `toml:"dial_timeout" yaml:"dial_timeout" mapstructure:"dial_timeout"`
-		Username    string        `toml:"username" yaml:"username" mapstructure:"username"`
-		Password    string        `toml:"password" yaml:"password" mapstructure:"password"`
-		LeaseTTL    int64         `toml:"lease_ttl" yaml:"lease_ttl" mapstructure:"lease_ttl"`
+	ETCD *Cfgs `toml:"etcd" yaml:"etcd" mapstructure:"etcd"`
+}
+
+// UnmarshalTOML 供 TomlParser 使用，拆分规则同 UnmarshalMap
+func (c *Cfgs) UnmarshalTOML(data interface{}) error {
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("etcd config must be a table")
+	}
+	return c.UnmarshalMap(table)
+}
+
+// UnmarshalMap 拆分顶层字段与命名子表，供 ViperParser 使用
+func (c *Cfgs) UnmarshalMap(table map[string]interface{}) error {
+	top := map[string]interface{}{}
+	c.Named = map[string]*Cfg{}
+	for key, value := range table {
+		if sub, ok := value.(map[string]interface{}); ok && key != "tls" {
+			cfg, err := decodeCfg(sub)
+			if err != nil {
+				return fmt.Errorf("etcd.%s: %w", key, err)
+			}
+			c.Named[key] = cfg
+			continue
+		}
+		top[key] = value
+	}
+	if len(top) > 0 {
+		cfg, err := decodeCfg(top)
+		if err != nil {
+			return fmt.Errorf("etcd: %w", err)
+		}
+		c.Default = cfg
+	}
+	return nil
+}
+
+// decodeCfg 将表解码为 Cfg，时长字段支持字符串，整数与 time.Duration 一致按纳秒处理
+func decodeCfg(table map[string]interface{}) (*Cfg, error) {
+	cfg := &Cfg{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           cfg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = decoder.Decode(table); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-		TLS struct {
-			CertFile string `toml:"cert_file" yaml:"cert_file" mapstructure:"cert_file"`
-			KeyFile  string `toml:"key_file" yaml:"key_file" mapstructure:"key_file"`
-			CAFile   string `toml:"ca_file" yaml:"ca_file" mapstructure:"ca_file"`
-		} `toml:"tls" yaml:"tls" mapstructure:"tls"`
-	} `toml:"etcd" yaml:"etcd" mapstructure:"etcd"`
+// Get 返回名称对应的配置，default 优先取顶层字段，其次取 [etcd.default]
+func (c *Cfgs) Get(name string) *Cfg {
+	if c == nil {
+		return nil
+	}
+	if name == DefaultName && c.Default != nil && len(c.Default.Endpoints) > 0 {
+		return c.Default
+	}
+	return c.Named[name]
 }
 
-var defaultClient *Client
-var once sync.Once
+var (
+	lock    sync.Mutex
+	clients = map[string]*Client{}
+)
 
 type Client struct {
+	name   string
 	client *clientv3.Client
-	cfg    *Config
+	cfg    *Cfg
 }
 
-// Start 初始化 etcd client，支持 TLS 和认证
-func (c *Client) Start() error {
-	cfg := config.Get(&Config{}).(*Config) // 假设 config.Get 是你的配置获取函数
-	c.cfg = cfg
+// Get 获取默认客户端，首次调用时创建；创建失败返回错误，下次调用重试
+func Get() (*Client, error) {
+	return Named(DefaultName)
+}
 
-	if cfg.ETCD == nil {
-		return fmt.Errorf("etcd configuration is not provided")
+// Named 获取 [etcd.<name>] 对应的客户端，首次调用时创建
+func Named(name string) (*Client, error) {
+	if name == "" {
+		name = DefaultName
 	}
+	lock.Lock()
+	defer lock.Unlock()
+	if c := clients[name]; c != nil {
+		return c, nil
+	}
+
+	conf, ok := config.Get(&Config{}).(*Config)
+	if !ok || conf.ETCD == nil {
+		return nil, fmt.Errorf("etcd configuration is not provided")
+	}
+	cfg := conf.ETCD.Get(name)
+	if cfg == nil {
+		return nil, fmt.Errorf("etcd#%s not configed", name)
+	}
+	c, err := New(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	clients[name] = c
+	return c, nil
+}
 
+// New 根据配置创建客户端并探测各节点，多数节点不可用时返回错误
+func New(name string, cfg *Cfg) (*Client, error) {
+	c := *cfg
+	cfg = &c
 	// 设置默认值
-	if len(cfg.ETCD.Endpoints) == 0 {
-		return fmt.Errorf("etcd endpoints cannot be empty")
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd#%s endpoints cannot be empty", name)
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
 	}
-	if cfg.ETCD.DialTimeout <= 0 {
-		cfg.ETCD.DialTimeout = 5 * time.Second // 默认 5 秒
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 15 // 默认 15 秒（介于 10~30 之间）
 	}
-	if cfg.ETCD.LeaseTTL <= 0 {
-		cfg.ETCD.LeaseTTL = 15 // 默认 15 秒（介于 10~30 之间）
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = 3 * time.Second
 	}
 
 	// 构建 clientv3 配置
 	cliCfg := clientv3.Config{
-		Endpoints:   cfg.ETCD.Endpoints,
-		DialTimeout: cfg.ETCD.DialTimeout,
-		Username:    cfg.ETCD.Username,
-		Password:    cfg.ETCD.Password,
+		Endpoints:            cfg.Endpoints,
+		DialTimeout:          cfg.DialTimeout,
+		Username:             cfg.Username,
+		Password:             cfg.Password,
+		AutoSyncInterval:     cfg.AutoSyncInterval,
+		DialKeepAliveTime:    cfg.KeepaliveTime,
+		DialKeepAliveTimeout: cfg.KeepaliveTimeout,
 	}
 
 	// 如果配置了 TLS，则启用安全连接
-	if cfg.ETCD.TLS.CertFile != "" || cfg.ETCD.TLS.KeyFile != "" || cfg.ETCD.TLS.CAFile != "" {
-		tlsConfig, err := newTLSConfig(struct{ CertFile, KeyFile, CAFile string }(cfg.ETCD.TLS))
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" || cfg.TLS.CAFile != "" {
+		tlsConfig, err := newTLSConfig(struct{ CertFile, KeyFile, CAFile string }(cfg.TLS))
 		if err != nil {
-			return fmt.Errorf("failed to create TLS config: %w", err)
+			return nil, fmt.Errorf("failed to create TLS config: %w", err)
 		}
 		cliCfg.TLS = tlsConfig
 	}
@@ -78,21 +197,17 @@ func (c *Client) Start() error {
 	// 创建客户端
 	client, err := clientv3.New(cliCfg)
 	if err != nil {
-		return fmt.Errorf("failed to create etcd client: %w", err)
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
 	}
+	r := &Client{name: name, client: client, cfg: cfg}
 
-	// 可选：测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if _, err := client.Status(ctx, cfg.ETCD.Endpoints[0]); err != nil {
+	if err = r.Health(context.Background()); err != nil {
 		client.Close()
-		return fmt.Errorf("etcd connection failed: %w", err)
+		return nil, fmt.Errorf("etcd#%s connection failed: %w", name, err)
 	}
 
-	c.client = client
-	zap.L().Info("etcd client started successfully", zap.Strings("endpoints", cfg.ETCD.Endpoints))
-	return nil
+	xlog.Infof(context.Background(), "etcd#%s client started, endpoints: %v", name, cfg.Endpoints)
+	return r, nil
 }
 
 // newTLSConfig 根据配置构建 TLS 配置
@@ -109,7 +224,7 @@ func newTLSConfig(tlsCfg struct {
 	}
 
 	caCertPool := x509.NewCertPool()
-	caCert, err := ioutil.ReadFile(tlsCfg.CAFile)
+	caCert, err := os.ReadFile(tlsCfg.CAFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CA file: %w", err)
 	}
@@ -124,24 +239,85 @@ func newTLSConfig(tlsCfg struct {
 	}, nil
 }
 
-// GetDefaultClient 获取单例客户端
-func Get() *Client {
-	once.Do(func() {
-		defaultClient = &Client{}
-		if err := defaultClient.Start(); err != nil {
-			panic(fmt.Sprintf("failed to start etcd client: %v", err))
+// Probe 并发探测每个节点，返回 endpoint -> 错误（nil 表示健康）
+func (c *Client) Probe(ctx context.Context) map[string]error {
+	endpoints := c.client.Endpoints()
+	result := make(map[string]error, len(endpoints))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, ep := range endpoints {
+		wg.Add(1)
+		go func(ep string) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, c.cfg.ProbeTimeout)
+			defer cancel()
+			_, err := c.client.Status(probeCtx, ep)
+			mu.Lock()
+			result[ep] = err
+			mu.Unlock()
+		}(ep)
+	}
+	wg.Wait()
+	return result
+}
+
+// Health 探测所有节点，多数节点不可用时返回包含各节点错误的 error
+func (c *Client) Health(ctx context.Context) error {
+	var errs []error
+	for ep, err := range c.Probe(ctx) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ep, err))
 		}
-	})
-	return defaultClient
+	}
+	if n := len(c.client.Endpoints()); len(errs) > 0 && len(errs)*2 >= n {
+		return errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		xlog.Warnf(ctx, "etcd#%s some endpoints are unhealthy: %v", c.name, errors.Join(errs...))
+	}
+	return nil
 }
 
+// DefaultClient 返回底层 clientv3 客户端
 func (c *Client) DefaultClient() *clientv3.Client {
 	return c.client
 }
 
-// Close 关闭客户端（可选）
+// Cfg 返回填充默认值后的配置
+func (c *Client) Cfg() *Cfg {
+	return c.cfg
+}
+
+// LeaseTTL 返回 lease_ttl 配置的租约 TTL（秒），未配置时为 15
+func (c *Client) LeaseTTL() int64 {
+	return c.cfg.LeaseTTL
+}
+
+// Close 关闭客户端，之后再次获取同名客户端会重新创建
 func (c *Client) Close() {
-	if c.client != nil {
-		c.client.Close()
+	lock.Lock()
+	if clients[c.name] == c {
+		delete(clients, c.name)
 	}
+	lock.Unlock()
+	c.client.Close()
+}
+
+// Valid 检查指定名称的客户端是否可用，names 为空时检查默认客户端
+func Valid(names ...string) error {
+	if len(names) == 0 {
+		names = []string{DefaultName}
+	}
+	for _, name := range names {
+		c, err := Named(name)
+		if err == nil {
+			err = c.Health(context.Background())
+		}
+		if err != nil {
+			return fmt.Errorf("etcd#%s is invalid, %w", name, err)
+		}
+	}
+	return nil
 }
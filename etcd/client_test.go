@@ -0,0 +1,78 @@
+package etcd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/liweiming-nova/common/config/options"
+	"github.com/liweiming-nova/common/config/parser"
+)
+
+func checkCfgs(t *testing.T, conf *Config) {
+	t.Helper()
+	def := conf.ETCD.Get(DefaultName)
+	if def == nil || def.DialTimeout != 3*time.Second || def.LeaseTTL != 10 || def.TLS.CAFile != "ca.pem" {
+		t.Fatalf("unexpected default cfg %+v", def)
+	}
+	named := conf.ETCD.Get("config")
+	if named == nil || len(named.Endpoints) != 2 || named.DialTimeout != 5*time.Second || named.AutoSyncInterval != time.Minute {
+		t.Fatalf("unexpected named cfg %+v", named)
+	}
+	// 整数时长与 time.Duration 一致按纳秒处理
+	if named.ProbeTimeout != 500 {
+		t.Fatalf("expected integer duration in nanoseconds, got %v", named.ProbeTimeout)
+	}
+	if conf.ETCD.Get("missing") != nil {
+		t.Fatal("expected nil for missing client")
+	}
+}
+
+func TestCfgs(t *testing.T) {
+	data := `
+[etcd]
+endpoints = ["http://localhost:2379"]
+dial_timeout = "3s"
+lease_ttl = 10
+[etcd.tls]
+ca_file = "ca.pem"
+
+[etcd.config]
+endpoints = ["http://10.0.0.1:2379", "http://10.0.0.2:2379"]
+dial_timeout = "5s"
+auto_sync_interval = "1m"
+probe_timeout = 500
+`
+	var conf Config
+	if _, err := toml.Decode(data, &conf); err != nil {
+		t.Fatal(err)
+	}
+	checkCfgs(t, &conf)
+}
+
+func TestCfgsViper(t *testing.T) {
+	data := `
+etcd:
+  endpoints: ["http://localhost:2379"]
+  dial_timeout: 3s
+  lease_ttl: 10
+  tls:
+    ca_file: ca.pem
+  config:
+    endpoints: ["http://10.0.0.1:2379", "http://10.0.0.2:2379"]
+    dial_timeout: 5s
+    auto_sync_interval: 1m
+    probe_timeout: 500
+`
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var conf Config
+	if err := parser.NewViperParser().Unmarshal(&conf, &options.Options{Sources: []string{file}}); err != nil {
+		t.Fatal(err)
+	}
+	checkCfgs(t, &conf)
+}
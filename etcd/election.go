@@ -0,0 +1,220 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// ErrNoLeader 当前没有 leader
+var ErrNoLeader = concurrency.ErrElectionNoLeader
+
+// Election 基于 concurrency.Election 的 leader 选举
+//
+// 每个参选者持有一个 session（租约），租约丢失即失去 leader 身份，
+// 此时 Done() 关闭，再次 Campaign 时会创建新的 session
+type Election struct {
+	client *clientv3.Client
+	prefix string
+	ttl    int64
+
+	mu       sync.Mutex
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+// NewElection 创建选举，prefix 为选举名对应的 key 前缀，ttl 为租约秒数（<=0 时取 15）
+func NewElection(client *clientv3.Client, prefix string, ttl int64) *Election {
+	if ttl <= 0 {
+		ttl = 15
+	}
+	return &Election{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Campaign 参选并阻塞直到成为 leader；ctx 结束或租约丢失时返回错误
+func (e *Election) Campaign(ctx context.Context, value string) error {
+	e.mu.Lock()
+	session, err := e.ensureSession(ctx)
+	if err != nil {
+		e.mu.Unlock()
+		return err
+	}
+	election := concurrency.NewElection(session, e.prefix)
+	e.election = election
+	e.mu.Unlock()
+
+	// 等待期间租约丢失时不再继续等待
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-session.Done():
+			cancel()
+		case <-cctx.Done():
+		}
+	}()
+
+	if err = election.Campaign(cctx, value); err != nil {
+		select {
+		case <-session.Done():
+			return concurrency.ErrSessionExpired
+		default:
+		}
+		return err
+	}
+	return nil
+}
+
+// ensureSession 返回可用的 session，没有或已失效时重新申请租约
+func (e *Election) ensureSession(ctx context.Context) (*concurrency.Session, error) {
+	if e.session != nil {
+		select {
+		case <-e.session.Done():
+			e.session.Close()
+			e.session = nil
+		default:
+			return e.session, nil
+		}
+	}
+	lease, err := e.client.Grant(ctx, e.ttl)
+	if err != nil {
+		return nil, err
+	}
+	session, err := concurrency.NewSession(e.client, concurrency.WithLease(lease.ID))
+	if err != nil {
+		revokeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		e.client.Revoke(revokeCtx, lease.ID)
+		return nil, err
+	}
+	e.session = session
+	return session, nil
+}
+
+// Resign 放弃 leader 身份（或退出等待）并释放租约
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var err error
+	if e.election != nil {
+		err = e.election.Resign(ctx)
+		e.election = nil
+	}
+	if e.session != nil {
+		e.session.Close()
+		e.session = nil
+	}
+	return err
+}
+
+// Done 租约丢失时关闭，leader 应在此时停止工作；尚未参选时返回已关闭的 channel
+func (e *Election) Done() <-chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		ch := make(chan struct{})
+		close(ch)
+		return ch
+	}
+	return e.session.Done()
+}
+
+// Leader 返回当前 leader 的 value，没有 leader 时返回 ErrNoLeader
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	value, _, err := e.leader(ctx)
+	return value, err
+}
+
+func (e *Election) leader(ctx context.Context) (string, int64, error) {
+	resp, err := e.client.Get(ctx, e.keyPrefix(), clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", resp.Header.Revision, ErrNoLeader
+	}
+	return string(resp.Kvs[0].Value), resp.Header.Revision, nil
+}
+
+// Observe 推送 leader 的变化，"" 表示当前没有 leader；ctx 结束时关闭 channel
+//
+// 不需要参选即可观察，连接异常时按退避重试
+func (e *Election) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string, 1)
+	go e.observe(ctx, ch)
+	return ch
+}
+
+func (e *Election) observe(ctx context.Context, ch chan string) {
+	defer close(ch)
+	var (
+		last    string
+		pushed  bool
+		backoff = 100 * time.Millisecond
+	)
+	push := func(value string) bool {
+		if pushed && value == last {
+			return true
+		}
+		select {
+		case ch <- value:
+			last, pushed = value, true
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	wait := func() bool {
+		select {
+		case <-time.After(backoff):
+			if backoff *= 2; backoff > 10*time.Second {
+				backoff = 10 * time.Second
+			}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for ctx.Err() == nil {
+		value, rev, err := e.leader(ctx)
+		if err != nil && err != ErrNoLeader {
+			if !wait() {
+				return
+			}
+			continue
+		}
+		if !push(value) {
+			return
+		}
+
+		// 每轮 watch 使用独立的 ctx，中断后取消，避免底层 watcher 累积
+		wctx, wcancel := context.WithCancel(ctx)
+		wch := e.client.Watch(clientv3.WithRequireLeader(wctx), e.keyPrefix(), clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		for resp := range wch {
+			if resp.Err() != nil {
+				break
+			}
+			backoff = 100 * time.Millisecond
+			if value, _, err = e.leader(ctx); err != nil && err != ErrNoLeader {
+				break
+			}
+			if !push(value) {
+				wcancel()
+				return
+			}
+		}
+		wcancel()
+		if !wait() {
+			return
+		}
+	}
+}
+
+// keyPrefix 与 concurrency.Election 保持一致
+func (e *Election) keyPrefix() string {
+	return e.prefix + "/"
+}
@@ -0,0 +1,124 @@
+package etcdtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// expectPairs 读取推送直到实例 key 与 want 一致
+func expectPairs(t *testing.T, ch chan []*discovery.KVPair, want ...string) {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	var last []string
+	for {
+		select {
+		case pairs, ok := <-ch:
+			if !ok {
+				t.Fatal("watch channel closed")
+			}
+			last = last[:0]
+			for _, pair := range pairs {
+				last = append(last, pair.Key)
+			}
+			if strings.Join(last, ",") == strings.Join(want, ",") {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("instances %v not pushed, last %v", want, last)
+		}
+	}
+}
+
+func put(t *testing.T, key, value string) int64 {
+	t.Helper()
+	resp, err := client.Put(context.Background(), key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Header.Revision
+}
+
+func del(t *testing.T, key string) {
+	t.Helper()
+	if _, err := client.Delete(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoveryWatch(t *testing.T) {
+	const path = "/services/disctest"
+	if _, err := client.Delete(context.Background(), path, clientv3.WithPrefix()); err != nil {
+		t.Fatal(err)
+	}
+	d, err := discovery.NewEtcdDiscovery(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := d.WatchService()
+	expectPairs(t, ch)
+
+	// 增量应用 PUT/DELETE
+	put(t, path+"/a", "a")
+	expectPairs(t, ch, path+"/a")
+	put(t, path+"/b", "b")
+	expectPairs(t, ch, path+"/a", path+"/b")
+	del(t, path+"/a")
+	expectPairs(t, ch, path+"/b")
+
+	// 最后一个实例下线时推送空列表
+	del(t, path+"/b")
+	select {
+	case pairs := <-ch:
+		if len(pairs) != 0 {
+			t.Fatalf("expected empty instances, got %v", pairs)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("empty instances not pushed")
+	}
+
+	// 连接中断期间的变更在恢复后从已应用的 revision 之后继续
+	proxy.pause()
+	put(t, path+"/c", "c")
+	proxy.resume()
+	expectPairs(t, ch, path+"/c")
+	if got := d.GetServices(); len(got) != 1 || got[0].Value != "c" {
+		t.Fatalf("unexpected instances %v", got)
+	}
+
+	// 中断期间 revision 被压缩：watch 返回 CompactRevision 后全量同步
+	proxy.pause()
+	put(t, path+"/d", "d")
+	del(t, path+"/c")
+	rev := put(t, path+"/e", "e")
+	if _, err = client.Compact(context.Background(), rev); err != nil {
+		proxy.resume()
+		t.Fatal(err)
+	}
+	proxy.resume()
+	expectPairs(t, ch, path+"/d", path+"/e")
+
+	// Close 结束订阅，但不关闭进程内共享的 etcd 客户端
+	d.Close()
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-ch:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("watch channel not closed after Close")
+		}
+	}
+	other, err := discovery.NewEtcdDiscovery(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if got := other.GetServices(); len(got) != 2 || other.Err() != nil {
+		t.Fatalf("shared client should stay open after Close, got %v: %v", got, other.Err())
+	}
+}
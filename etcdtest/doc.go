@@ -0,0 +1,4 @@
+// Package etcdtest 基于进程内 etcd 的集成测试
+//
+// 独立为模块，避免 etcd server 及其依赖进入库的 go.mod；在本目录执行 go test ./... 运行
+package etcdtest
@@ -0,0 +1,93 @@
+package etcdtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// revokeLeader 撤销当前 leader 的租约，模拟 leader 失联
+func revokeLeader(t *testing.T, prefix string) {
+	t.Helper()
+	resp, err := client.Get(context.Background(), prefix+"/", clientv3.WithFirstCreate()...)
+	if err != nil || len(resp.Kvs) == 0 {
+		t.Fatalf("leader key not found: %v", err)
+	}
+	if _, err = client.Revoke(context.Background(), clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectLeader(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case value := <-ch:
+			if value == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("leader %q not observed", want)
+		}
+	}
+}
+
+func TestElection(t *testing.T) {
+	ctx := context.Background()
+	first := etcd.NewElection(client, "/election/test", 5)
+	second := etcd.NewElection(client, "/election/test", 5)
+
+	observeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	observed := first.Observe(observeCtx)
+	expectLeader(t, observed, "")
+
+	if err := first.Campaign(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+	expectLeader(t, observed, "first")
+
+	// leader 存在时其他参选者阻塞
+	waitCtx, waitCancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer waitCancel()
+	if err := second.Campaign(waitCtx, "second"); err == nil {
+		t.Fatal("second campaign should block while first is leader")
+	}
+	if leader, err := second.Leader(ctx); err != nil || leader != "first" {
+		t.Fatalf("expected leader first, got %q %v", leader, err)
+	}
+
+	// Resign 后其他参选者当选
+	elected := make(chan error, 1)
+	go func() { elected <- second.Campaign(ctx, "second") }()
+	if err := first.Resign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-elected:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second was not elected after resign")
+	}
+	expectLeader(t, observed, "second")
+
+	// 租约丢失时 Done 关闭
+	revokeLeader(t, "/election/test")
+	select {
+	case <-second.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after lease revoked")
+	}
+	expectLeader(t, observed, "")
+
+	cancel()
+	for range observed {
+	}
+	second.Resign(ctx)
+}
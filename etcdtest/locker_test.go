@@ -0,0 +1,53 @@
+package etcdtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/utils/lock"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestEtcdLocker(t *testing.T) {
+	locker := lock.NewEtcdLocker(client, lock.WithEtcdLockPrefix("/test-locks/"))
+	ctx := context.Background()
+
+	token, err := locker.Acquire(ctx, "job", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 持锁期间其他调用者阻塞直到超时
+	waitCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	if _, err = locker.Acquire(waitCtx, "job", 5*time.Second); err != lock.ErrLockTimeout {
+		t.Fatalf("expected ErrLockTimeout while held, got %v", err)
+	}
+
+	if ok, err := locker.Release("job", "other-token"); ok || err != lock.ErrReleaseFailed {
+		t.Fatalf("expected ErrReleaseFailed for mismatched token, got %v %v", ok, err)
+	}
+	if ok, err := locker.Release("job", token); !ok || err != nil {
+		t.Fatalf("release failed: %v %v", ok, err)
+	}
+
+	// 释放后可以再次获得；ttl 取 1s 使续约间隔足够短
+	token, err = locker.Acquire(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 租约丢失后锁可能已被他人持有，Release 返回 ErrReleaseFailed
+	resp, err := client.Get(ctx, "/test-locks/job/", clientv3.WithPrefix())
+	if err != nil || len(resp.Kvs) != 1 {
+		t.Fatalf("lock key not found: %v", err)
+	}
+	if _, err = client.Revoke(ctx, clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		t.Fatal(err)
+	}
+	// 等待下一次续约发现租约已失效
+	time.Sleep(2 * time.Second)
+	if ok, err := locker.Release("job", token); ok || err != lock.ErrReleaseFailed {
+		t.Fatalf("expected ErrReleaseFailed for expired lease, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,171 @@
+package etcdtest
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/config"
+	"github.com/liweiming-nova/common/config/options"
+	"github.com/liweiming-nova/common/config/parser"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+)
+
+var (
+	// client 直连内嵌 etcd，用于准备数据、撤销租约、压缩等操作
+	client *clientv3.Client
+	// proxy 位于配置中的 etcd 地址与内嵌 etcd 之间，用于模拟连接中断
+	proxy *tcpProxy
+)
+
+// TestMain 启动单节点 etcd，并写入指向代理地址的 [etcd] 配置，被测代码通过 etcd.Named 获取客户端
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "etcdtest")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := embed.NewConfig()
+	cfg.Dir = filepath.Join(dir, "data")
+	cfg.ZapLoggerBuilder = embed.NewZapLoggerBuilder(zap.NewNop())
+	clientURL, peerURL := freeURL(), freeURL()
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer e.Close()
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		panic("embedded etcd is not ready")
+	}
+
+	client, err = clientv3.New(clientv3.Config{Endpoints: []string{clientURL.String()}, DialTimeout: 5 * time.Second, Logger: zap.NewNop()})
+	if err != nil {
+		panic(err)
+	}
+	defer client.Close()
+
+	proxy = newTCPProxy(clientURL.Host)
+	defer proxy.close()
+
+	file := filepath.Join(dir, "app.toml")
+	data := fmt.Sprintf("[etcd]\nendpoints = [\"http://%s\"]\ndial_timeout = \"5s\"\nlease_ttl = 5\n", proxy.addr())
+	if err = os.WriteFile(file, []byte(data), 0o644); err != nil {
+		panic(err)
+	}
+	config.NewConfig(parser.NewTomlParser(), options.WithCfgSource(file))
+
+	return m.Run()
+}
+
+// freeURL 返回本机一个空闲端口的 http 地址
+func freeURL() url.URL {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer lis.Close()
+	return url.URL{Scheme: "http", Host: lis.Addr().String()}
+}
+
+// tcpProxy 转发 TCP 连接，pause 期间断开所有连接并拒绝新连接
+type tcpProxy struct {
+	lis    net.Listener
+	target string
+
+	mu     sync.Mutex
+	paused bool
+	conns  map[net.Conn]struct{}
+}
+
+func newTCPProxy(target string) *tcpProxy {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	p := &tcpProxy{lis: lis, target: target, conns: map[net.Conn]struct{}{}}
+	go p.serve()
+	return p
+}
+
+func (p *tcpProxy) addr() string {
+	return p.lis.Addr().String()
+}
+
+func (p *tcpProxy) serve() {
+	for {
+		conn, err := p.lis.Accept()
+		if err != nil {
+			return
+		}
+		go p.forward(conn)
+	}
+}
+
+func (p *tcpProxy) forward(conn net.Conn) {
+	upstream, err := net.Dial("tcp", p.target)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	p.mu.Lock()
+	if p.paused {
+		p.mu.Unlock()
+		conn.Close()
+		upstream.Close()
+		return
+	}
+	p.conns[conn], p.conns[upstream] = struct{}{}, struct{}{}
+	p.mu.Unlock()
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, conn); done <- struct{}{} }()
+	go func() { io.Copy(conn, upstream); done <- struct{}{} }()
+	<-done
+	conn.Close()
+	upstream.Close()
+	p.mu.Lock()
+	delete(p.conns, conn)
+	delete(p.conns, upstream)
+	p.mu.Unlock()
+}
+
+// pause 断开现有连接，resume 之前的新连接会被立即关闭
+func (p *tcpProxy) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
+
+func (p *tcpProxy) resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.mu.Unlock()
+}
+
+func (p *tcpProxy) close() {
+	p.lis.Close()
+	p.pause()
+}
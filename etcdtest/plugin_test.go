@@ -0,0 +1,63 @@
+package etcdtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/app/plugins"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestElectionPluginLeadershipLost(t *testing.T) {
+	elected := make(chan context.Context, 2)
+	revoked := make(chan struct{}, 2)
+	plugin := plugins.NewElectionPlugin("job",
+		plugins.WithElectionValue("node-1"),
+		plugins.WithOnElected(func(ctx context.Context) {
+			elected <- ctx
+			<-ctx.Done()
+		}),
+		plugins.WithOnRevoked(func() { revoked <- struct{}{} }),
+	)
+	if err := plugin.Start(plugins.NewPluginContext("test", "")); err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Stop()
+
+	var leaderCtx context.Context
+	select {
+	case leaderCtx = <-elected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin was not elected")
+	}
+	if !plugin.IsLeader() {
+		t.Fatal("expected IsLeader after elected")
+	}
+
+	// 撤销租约模拟 leader 失联，OnElected 的 ctx 应被取消
+	resp, err := client.Get(context.Background(), "/election/job/", clientv3.WithFirstCreate()...)
+	if err != nil || len(resp.Kvs) == 0 {
+		t.Fatalf("leader key not found: %v", err)
+	}
+	if _, err = client.Revoke(context.Background(), clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnElected ctx not cancelled after leadership lost")
+	}
+	select {
+	case <-revoked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnRevoked not called")
+	}
+
+	// 失去身份后重新参选
+	select {
+	case <-elected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin was not re-elected")
+	}
+}
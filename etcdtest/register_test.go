@@ -0,0 +1,119 @@
+package etcdtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/register"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const registerKey = "/services/regtest/127.0.0.1:9000"
+
+// expectState 等待注册状态回调
+func expectState(t *testing.T, states <-chan bool, want bool) {
+	t.Helper()
+	select {
+	case got := <-states:
+		if got != want {
+			t.Fatalf("registered = %v, want %v", got, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("state %v not reported", want)
+	}
+}
+
+// registered 返回注册 key 当前的值与租约，key 不存在时返回 nil
+func registered(t *testing.T) (*instance.ServiceInstance, clientv3.LeaseID) {
+	t.Helper()
+	resp, err := client.Get(context.Background(), registerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, 0
+	}
+	return instance.FromValue(string(resp.Kvs[0].Value)), clientv3.LeaseID(resp.Kvs[0].Lease)
+}
+
+func waitRegistered(t *testing.T, what string, cond func(si *instance.ServiceInstance, lease clientv3.LeaseID) bool) (*instance.ServiceInstance, clientv3.LeaseID) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		si, lease := registered(t)
+		if cond(si, lease) {
+			return si, lease
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRegisterLeaseLoss(t *testing.T) {
+	states := make(chan bool, 10)
+	r, err := register.NewEtcdRegister(register.WithLeaseTTL(2), register.WithStateListener(func(ok bool) { states <- ok }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err = r.Register("regtest", "127.0.0.1:9000", map[string]string{"version": "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, states, true)
+	si, lease := registered(t)
+	if si == nil || si.Metadata["version"] != "v1" {
+		t.Fatalf("unexpected registration %+v", si)
+	}
+
+	// 租约被撤销：key 随之删除，续约结束后申请新租约重新写入同一值
+	if _, err = client.Revoke(context.Background(), lease); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, states, false)
+	expectState(t, states, true)
+	recovered, newLease := waitRegistered(t, "re-registration", func(si *instance.ServiceInstance, l clientv3.LeaseID) bool {
+		return si != nil && l != lease
+	})
+	if recovered.Metadata["version"] != "v1" || recovered.RegisteredAt != si.RegisteredAt {
+		t.Fatalf("re-registered value changed: %+v", recovered)
+	}
+
+	// 注册中心不可达时按退避重试，恢复连接后重新写入
+	proxy.pause()
+	if _, err = client.Revoke(context.Background(), newLease); err != nil {
+		proxy.resume()
+		t.Fatal(err)
+	}
+	expectState(t, states, false)
+	time.Sleep(time.Second)
+	if si, _ := registered(t); si != nil {
+		proxy.resume()
+		t.Fatal("instance should stay unregistered while etcd is unreachable")
+	}
+	proxy.resume()
+	expectState(t, states, true)
+	waitRegistered(t, "re-registration after reconnect", func(si *instance.ServiceInstance, l clientv3.LeaseID) bool {
+		return si != nil && l != newLease
+	})
+
+	// 重新注册后元数据更新使用新租约
+	if err = r.UpdateMetadata("regtest", "127.0.0.1:9000", map[string]string{"version": "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if si, _ := registered(t); si == nil || si.Metadata["version"] != "v2" {
+		t.Fatalf("metadata not updated: %+v", si)
+	}
+
+	if err = r.Unregister("regtest", "127.0.0.1:9000"); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, states, false)
+	if si, _ := registered(t); si != nil {
+		t.Fatal("instance should be removed after unregister")
+	}
+}
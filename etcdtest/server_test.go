@@ -0,0 +1,82 @@
+package etcdtest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/server"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+)
+
+func TestServerUpdateMetadata(t *testing.T) {
+	addr := freeURL().Host
+	_, port, _ := net.SplitHostPort(addr)
+	key := "/services/metatest/127.0.0.1:" + port
+
+	s, err := server.NewGrpcServer(&server.GrpcConfig{DialAddr: addr, AdvertiseHost: "127.0.0.1", ServiceName: "metatest"}, "metatest", func(*grpc.Server) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.UpdateMetadata(map[string]string{"version": "v1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := discovery.NewEtcdDiscovery("/services/metatest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ch := d.WatchService()
+	expectPairs(t, ch)
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	expectPairs(t, ch, key)
+	resp, err := client.Get(context.Background(), key)
+	if err != nil || len(resp.Kvs) == 0 {
+		t.Fatalf("instance not registered: %v", err)
+	}
+	lease := clientv3.LeaseID(resp.Kvs[0].Lease)
+	registeredAt := instance.FromValue(string(resp.Kvs[0].Value)).RegisteredAt
+
+	// 元数据与权重的更新沿用原租约推送给订阅方，注册时间不变
+	if err = s.UpdateMetadata(map[string]string{"version": "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetWeight(3); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(10 * time.Second)
+	for {
+		var si *instance.ServiceInstance
+		select {
+		case pairs := <-ch:
+			if len(pairs) != 1 {
+				t.Fatalf("expected 1 instance, got %v", pairs)
+			}
+			si = instance.FromValue(pairs[0].Value)
+		case <-timeout:
+			t.Fatal("metadata update not pushed")
+		}
+		if si.Metadata["version"] != "v2" || si.Weight() != 3 {
+			continue
+		}
+		if si.RegisteredAt != registeredAt {
+			t.Fatalf("registered_at changed from %d to %d", registeredAt, si.RegisteredAt)
+		}
+		break
+	}
+	if resp, err = client.Get(context.Background(), key); err != nil || len(resp.Kvs) == 0 {
+		t.Fatalf("instance lost after update: %v", err)
+	}
+	if got := clientv3.LeaseID(resp.Kvs[0].Lease); got != lease {
+		t.Fatalf("update should keep lease %x, got %x", lease, got)
+	}
+}
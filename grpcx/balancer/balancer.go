@@ -0,0 +1,158 @@
+package balancer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/client"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/resolver"
+	gbalancer "google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// policyPrefix 基于 Selector 的负载均衡策略名前缀，避免与 gRPC 内置策略（如 round_robin）冲突
+const policyPrefix = "selector_"
+
+// PolicyName 返回选择器对应的负载均衡策略名，如 score -> selector_score
+func PolicyName(selectMode string) string {
+	return policyPrefix + strings.ToLower(strings.TrimSpace(selectMode))
+}
+
+// Option 负载均衡策略选项
+type Option func(b *builder)
+
+// WithBreaker 为每个子连接启用节点级熔断，熔断中的节点不参与挑选
+func WithBreaker(cfg *client.BreakerCfg) Option {
+	return func(b *builder) {
+		b.breakerCfg = cfg
+	}
+}
+
+// Register 将 client 中注册的选择器注册为 gRPC 负载均衡策略，重复注册时覆盖之前的选项
+// 用法：grpc.WithDefaultServiceConfig(resolver.ServiceConfig(balancer.PolicyName("score"), true))
+func Register(selectMode string, opts ...Option) {
+	b := &builder{name: PolicyName(selectMode), selectMode: selectMode}
+	for _, opt := range opts {
+		opt(b)
+	}
+	gbalancer.Register(b)
+}
+
+func init() {
+	Register(client.SelectModeRoundRobin)
+	Register(client.SelectModeRandom)
+	Register(client.SelectModeScore)
+	Register(client.SelectModeWeightedRoundRobin)
+	Register(client.SelectModeP2C)
+	Register(client.SelectModeConsistentHash)
+}
+
+// builder 为每个 ClientConn 创建独立的选择器与熔断器，避免不同服务间共享 WRR 权重、P2C EWMA 等状态
+type builder struct {
+	name       string
+	selectMode string
+	breakerCfg *client.BreakerCfg
+}
+
+func (b *builder) Name() string {
+	return b.name
+}
+
+func (b *builder) Build(cc gbalancer.ClientConn, opts gbalancer.BuildOptions) gbalancer.Balancer {
+	pb := &pickerBuilder{
+		target:     opts.Target.Endpoint(),
+		selector:   client.GetSelector(b.selectMode, nil),
+		breakerCfg: b.breakerCfg,
+		breakers:   map[string]*client.Breaker{},
+	}
+	return base.NewBalancerBuilder(b.name, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+type pickerBuilder struct {
+	target     string
+	selector   client.Selector
+	breakerCfg *client.BreakerCfg
+	breakers   map[string]*client.Breaker // address -> 熔断器，跨 picker 保留状态；仅在 Build 中访问
+}
+
+// Build 在就绪子连接变化时重建 picker，熔断状态按地址保留，不再就绪的地址随之清理
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) gbalancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(gbalancer.ErrNoSubConnAvailable)
+	}
+
+	p := &picker{
+		target:   b.target,
+		selector: b.selector,
+		subConns: make(map[string]gbalancer.SubConn, len(info.ReadySCs)),
+		breakers: make(map[string]*client.Breaker, len(info.ReadySCs)),
+		nodes:    make([]*instance.ServiceInstance, 0, len(info.ReadySCs)),
+	}
+	for sc, scInfo := range info.ReadySCs {
+		node := &instance.ServiceInstance{Address: scInfo.Address.Addr}
+		if kv, ok := resolver.KVPairFromAddress(scInfo.Address); ok {
+			node = instance.FromValue(kv.Value)
+		}
+		p.subConns[node.Address] = sc
+		p.nodes = append(p.nodes, node)
+		if b.breakerCfg != nil {
+			if b.breakers[node.Address] == nil {
+				b.breakers[node.Address] = client.NewBreaker(b.target+"@"+node.Address, b.breakerCfg)
+			}
+			p.breakers[node.Address] = b.breakers[node.Address]
+		}
+	}
+	for addr := range b.breakers {
+		if _, ok := p.subConns[addr]; !ok {
+			delete(b.breakers, addr)
+		}
+	}
+	return p
+}
+
+type picker struct {
+	target   string
+	selector client.Selector
+	subConns map[string]gbalancer.SubConn // address -> SubConn
+	breakers map[string]*client.Breaker   // address -> 熔断器，未启用时为空
+	nodes    []*instance.ServiceInstance
+}
+
+func (p *picker) Pick(info gbalancer.PickInfo) (gbalancer.PickResult, error) {
+	nodes := p.nodes
+	if len(p.breakers) > 0 {
+		nodes = make([]*instance.ServiceInstance, 0, len(p.nodes))
+		for _, node := range p.nodes {
+			if p.breakers[node.Address].Ready() {
+				nodes = append(nodes, node)
+			}
+		}
+		if len(nodes) == 0 {
+			return gbalancer.PickResult{}, status.Error(codes.Unavailable, (&client.CircuitOpenError{Service: p.target}).Error())
+		}
+	}
+
+	node := p.selector.Pick(info.Ctx, nodes)
+	if node == nil {
+		return gbalancer.PickResult{}, gbalancer.ErrNoSubConnAvailable
+	}
+	sc, ok := p.subConns[node.Address]
+	if !ok {
+		p.selector.Done(node, client.DoneInfo{Err: gbalancer.ErrNoSubConnAvailable})
+		return gbalancer.PickResult{}, gbalancer.ErrNoSubConnAvailable
+	}
+	br := p.breakers[node.Address]
+	if !br.Allow() {
+		err := status.Error(codes.Unavailable, (&client.CircuitOpenError{Service: p.target, Endpoint: node.Address}).Error())
+		p.selector.Done(node, client.DoneInfo{Err: err})
+		return gbalancer.PickResult{}, err
+	}
+	start := time.Now()
+	return gbalancer.PickResult{SubConn: sc, Done: func(info gbalancer.DoneInfo) {
+		br.Record(info.Err)
+		p.selector.Done(node, client.DoneInfo{Err: info.Err, Duration: time.Since(start)})
+	}}, nil
+}
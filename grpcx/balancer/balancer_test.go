@@ -0,0 +1,98 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/client"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	gbalancer "google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	gresolver "google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSubConn struct {
+	gbalancer.SubConn
+	addr string
+}
+
+// lastSelector 总是挑选最后一个节点，并记录收到的候选与结果
+type lastSelector struct {
+	candidates []string
+	done       []error
+}
+
+func (s *lastSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	s.candidates = s.candidates[:0]
+	for _, node := range nodes {
+		s.candidates = append(s.candidates, node.Address)
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[len(nodes)-1]
+}
+
+func (s *lastSelector) Done(node *instance.ServiceInstance, info client.DoneInfo) {
+	s.done = append(s.done, info.Err)
+}
+
+func buildPicker(pb *pickerBuilder, addrs ...string) gbalancer.Picker {
+	info := base.PickerBuildInfo{ReadySCs: map[gbalancer.SubConn]base.SubConnInfo{}}
+	for _, addr := range addrs {
+		info.ReadySCs[&fakeSubConn{addr: addr}] = base.SubConnInfo{Address: gresolver.Address{Addr: addr}}
+	}
+	return pb.Build(info)
+}
+
+func TestPicker(t *testing.T) {
+	selector := &lastSelector{}
+	pb := &pickerBuilder{
+		target:     "user_server",
+		selector:   selector,
+		breakerCfg: &client.BreakerCfg{Enable: true, ConsecutiveFailures: 1, OpenDuration: time.Minute},
+		breakers:   map[string]*client.Breaker{},
+	}
+	p := buildPicker(pb, "a", "b")
+
+	// 选择器决定落点，调用结果回传给选择器
+	res, err := p.Pick(gbalancer.PickInfo{Ctx: context.Background()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	picked := res.SubConn.(*fakeSubConn).addr
+	if len(selector.candidates) != 2 || picked != selector.candidates[1] {
+		t.Fatalf("picker did not follow selector: candidates %v, picked %s", selector.candidates, picked)
+	}
+	failure := status.Error(codes.Unavailable, "down")
+	res.Done(gbalancer.DoneInfo{Err: failure})
+	if len(selector.done) != 1 || !errors.Is(selector.done[0], failure) {
+		t.Fatalf("selector Done not called with result: %v", selector.done)
+	}
+
+	// 熔断中的节点不再作为候选，重建 picker 后熔断状态保留
+	p = buildPicker(pb, "a", "b")
+	res, err = p.Pick(gbalancer.PickInfo{Ctx: context.Background()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selector.candidates) != 1 || selector.candidates[0] == picked {
+		t.Fatalf("tripped endpoint %s still offered: %v", picked, selector.candidates)
+	}
+	res.Done(gbalancer.DoneInfo{Err: failure})
+
+	// 全部熔断时返回 Unavailable
+	if _, err = p.Pick(gbalancer.PickInfo{Ctx: context.Background()}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable when all endpoints are open, got %v", err)
+	}
+
+	// 不再就绪的地址清理其熔断器
+	buildPicker(pb, "c")
+	if len(pb.breakers) != 1 || pb.breakers["c"] == nil {
+		t.Fatalf("stale breakers not pruned: %v", pb.breakers)
+	}
+}
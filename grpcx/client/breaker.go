@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerCfg 熔断配置，对应 [rpc.client.<name>.breaker]
+type BreakerCfg struct {
+	Enable              bool          `toml:"enable"`
+	FailureRatio        float64       `toml:"failure_ratio"`        // 窗口内失败率阈值，0 表示不按失败率熔断
+	MinRequests         int           `toml:"min_requests"`         // 按失败率熔断时窗口内的最少请求数，默认 20
+	ConsecutiveFailures int           `toml:"consecutive_failures"` // 连续失败次数阈值，默认 5
+	Window              time.Duration `toml:"window"`               // 统计窗口，默认 10s
+	OpenDuration        time.Duration `toml:"open_duration"`        // 熔断持续时间，到期后进入半开，默认 5s
+	HalfOpenRequests    int           `toml:"half_open_requests"`   // 半开状态允许的探测请求数，全部成功后恢复，默认 1
+}
+
+func (c *BreakerCfg) withDefaults() *BreakerCfg {
+	r := *c
+	if r.MinRequests <= 0 {
+		r.MinRequests = 20
+	}
+	if r.ConsecutiveFailures <= 0 {
+		r.ConsecutiveFailures = 5
+	}
+	if r.Window <= 0 {
+		r.Window = 10 * time.Second
+	}
+	if r.OpenDuration <= 0 {
+		r.OpenDuration = 5 * time.Second
+	}
+	if r.HalfOpenRequests <= 0 {
+		r.HalfOpenRequests = 1
+	}
+	return &r
+}
+
+// ErrCircuitOpen 熔断打开时返回的错误，可通过 errors.Is 判断
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError 熔断错误，Endpoint 为空表示服务级熔断或全部节点均已熔断
+type CircuitOpenError struct {
+	Service  string
+	Endpoint string
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Endpoint == "" {
+		return fmt.Sprintf("service %s: %s", e.Service, ErrCircuitOpen)
+	}
+	return fmt.Sprintf("service %s endpoint %s: %s", e.Service, e.Endpoint, ErrCircuitOpen)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker 单个熔断器，nil 表示未启用，所有方法对 nil 安全
+type breaker struct {
+	name string
+	cfg  *BreakerCfg
+
+	mu          sync.Mutex
+	state       breakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	openUntil   time.Time
+	probes      int // 半开状态下已放行的探测数
+	successes   int // 半开状态下探测成功数
+}
+
+func newBreaker(name string, cfg *BreakerCfg) *breaker {
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+	return &breaker{name: name, cfg: cfg, windowStart: time.Now()}
+}
+
+// ready 判断是否可能放行请求（不占用半开探测名额），用于节点筛选
+func (b *breaker) ready() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		return !time.Now().Before(b.openUntil)
+	case stateHalfOpen:
+		return b.probes < b.cfg.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+// allow 判断是否放行本次请求，半开状态下会占用探测名额
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Now().Before(b.openUntil) {
+			return false
+		}
+		b.setState(stateHalfOpen)
+		fallthrough
+	case stateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+// release 归还 allow 占用但最终未发起调用的半开探测名额
+func (b *breaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// record 记录一次调用结果
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	failed := isBreakerFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateHalfOpen:
+		if failed {
+			b.trip()
+			return
+		}
+		if b.successes++; b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(stateClosed)
+		}
+	case stateClosed:
+		if now := time.Now(); now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if !failed {
+			b.consecutive = 0
+			return
+		}
+		b.failures++
+		b.consecutive++
+		if b.consecutive >= b.cfg.ConsecutiveFailures ||
+			(b.cfg.FailureRatio > 0 && b.requests >= b.cfg.MinRequests &&
+				float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio) {
+			b.trip()
+		}
+	}
+}
+
+func (b *breaker) trip() {
+	b.openUntil = time.Now().Add(b.cfg.OpenDuration)
+	b.setState(stateOpen)
+}
+
+// setState 切换状态并重置计数，调用方需持有锁
+func (b *breaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	xlog.Warnf(context.Background(), "Circuit breaker %s state changed: %s -> %s", b.name, b.state, state)
+	b.state = state
+	b.windowStart = time.Now()
+	b.requests, b.failures, b.consecutive = 0, 0, 0
+	b.probes, b.successes = 0, 0
+}
+
+// isBreakerFailure 只有服务端或网络层面的错误计入熔断，参数错误、业务错误和调用方取消不计入
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return !errors.Is(err, context.Canceled)
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Breaker 对外暴露的熔断器，供 grpcx/balancer 等不经过连接池的调用方按节点熔断；nil 表示未启用，方法对 nil 安全
+type Breaker struct {
+	b *breaker
+}
+
+// NewBreaker 创建熔断器，cfg 为 nil 或未启用时返回 nil
+func NewBreaker(name string, cfg *BreakerCfg) *Breaker {
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+	return &Breaker{b: newBreaker(name, cfg.withDefaults())}
+}
+
+// Ready 判断是否可能放行请求，不占用半开探测名额
+func (b *Breaker) Ready() bool {
+	return b == nil || b.b.ready()
+}
+
+// Allow 判断是否放行本次请求，半开状态下占用探测名额
+func (b *Breaker) Allow() bool {
+	return b == nil || b.b.allow()
+}
+
+// Release 归还 Allow 占用但最终未发起调用的探测名额
+func (b *Breaker) Release() {
+	if b != nil {
+		b.b.release()
+	}
+}
+
+// Record 记录调用结果
+func (b *Breaker) Record(err error) {
+	if b != nil {
+		b.b.record(err)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker("user_server", (&BreakerCfg{Enable: true, ConsecutiveFailures: 2, OpenDuration: 50 * time.Millisecond}).withDefaults())
+
+	b.record(status.Error(codes.InvalidArgument, "bad request"))
+	b.record(status.Error(codes.Unavailable, "down"))
+	if !b.allow() {
+		t.Fatal("breaker should stay closed before threshold")
+	}
+	b.record(status.Error(codes.Unavailable, "down"))
+	if b.allow() || b.ready() {
+		t.Fatal("breaker should be open after consecutive failures")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("breaker should allow a probe when half-open")
+	}
+	if b.allow() {
+		t.Fatal("breaker should allow only one probe when half-open")
+	}
+	b.record(nil)
+	if !b.allow() {
+		t.Fatal("breaker should close after a successful probe")
+	}
+
+	err := error(&CircuitOpenError{Service: "user_server"})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("CircuitOpenError should match ErrCircuitOpen")
+	}
+}
+
+func TestBreakerHalfOpenPickFailure(t *testing.T) {
+	cfg := (&BreakerCfg{Enable: true, ConsecutiveFailures: 1, OpenDuration: 20 * time.Millisecond}).withDefaults()
+	p := &GrpcClientPool{
+		serviceName: "user_server",
+		endpoints:   map[string]*endpoint{},
+		cfg:         &Cfg{},
+		breaker:     newBreaker("user_server", cfg),
+	}
+	p.breaker.record(status.Error(codes.Unavailable, "down"))
+	if err := p.Call(context.Background(), "GetUser", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call should be rejected while open, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	// 半开状态下没有可用节点，挑选失败不能占用探测名额
+	for i := 0; i < 3; i++ {
+		if err := p.Call(context.Background(), "GetUser", nil, nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d should fail picking an endpoint, got %v", i, err)
+		}
+		if _, err := p.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "Watch"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("stream %d should fail picking an endpoint, got %v", i, err)
+		}
+	}
+	if !p.breaker.allow() {
+		t.Fatal("half-open probe should still be available after pick failures")
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/protobuf/proto"
+)
+
+// endpoint 单个服务节点及其连接
+type endpoint struct {
+	addr     string
+	kv       *discovery.KVPair
+	node     *instance.ServiceInstance // kv 解码后的实例
+	conns    []*grpc.ClientConn
+	idx      uint64
+	inflight int64    // 该节点上的在途调用数
+	breaker  *breaker // 节点级熔断器，未启用时为 nil
+}
+
+// setKV 更新注册信息并解码，调用方需持有连接池写锁
+func (e *endpoint) setKV(kv *discovery.KVPair) {
+	e.kv = kv
+	e.node = instance.FromValue(kv.Value)
+	e.node.Address = e.addr
+}
+
+// conn 在节点的多个连接之间轮询
+func (e *endpoint) conn() *grpc.ClientConn {
+	if len(e.conns) == 0 {
+		return nil
+	}
+	i := atomic.AddUint64(&e.idx, 1)
+	return e.conns[int(i%uint64(len(e.conns)))]
+}
+
+// available 只要有一个连接不处于失败状态即认为节点可用
+func (e *endpoint) available() bool {
+	for _, conn := range e.conns {
+		switch conn.GetState() {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// reconnect 对处于失败状态的连接重置退避并立即重连
+func (e *endpoint) reconnect() {
+	for _, conn := range e.conns {
+		if conn.GetState() == connectivity.TransientFailure {
+			conn.ResetConnectBackoff()
+			conn.Connect()
+		}
+	}
+}
+
+// acquire 占用一个在途计数，需在连接池读锁内调用，保证节点下线后的排空能等到该调用
+func (e *endpoint) acquire() {
+	atomic.AddInt64(&e.inflight, 1)
+}
+
+// release 释放 acquire 占用的在途计数
+func (e *endpoint) release() {
+	atomic.AddInt64(&e.inflight, -1)
+}
+
+// invoke 在该节点上发起一次 unary 调用，调用方需已通过 pick 占用在途计数
+func (e *endpoint) invoke(ctx context.Context, method string, req proto.Message, resp proto.Message) error {
+	return e.conn().Invoke(ctx, method, req, resp)
+}
+
+// drain 等待节点上的在途调用结束后关闭连接
+func (e *endpoint) drain(timeout time.Duration) {
+	if n := waitIdle(&e.inflight, timeout); n > 0 {
+		xlog.Warnf(context.Background(), "Drain endpoint %s timeout, %d calls still in flight", e.addr, n)
+	}
+	e.close()
+}
+
+func (e *endpoint) close() {
+	for _, conn := range e.conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
+
+// waitIdle 等待计数归零或超时，返回超时时剩余的计数
+func waitIdle(counter *int64, timeout time.Duration) int64 {
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(counter) > 0 && time.Now().Before(deadline) {
+		<-ticker.C
+	}
+	return atomic.LoadInt64(counter)
+}
@@ -1,20 +1,88 @@
 package client
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/liweiming-nova/common/grpcx/discovery"
 )
 
-func NewRpcClientPool(name string, cfg *Cfg) (r *GrpcClientPool, err error) {
+// 服务发现方式
+const (
+	DiscoveryEtcd   = "etcd"   // 从 etcd 的 /services/<name> 读取（默认）
+	DiscoveryStatic = "static" // 使用 addrs 配置的固定地址
+	DiscoveryFile   = "file"   // 从 discovery_file 读取并监听变化
+	DiscoveryDNS    = "dns"    // 定期解析 dns_target 的 A/AAAA 或 SRV 记录
+	DiscoveryConsul = "consul" // 通过 Consul 阻塞查询健康实例
+	DiscoveryNacos  = "nacos"  // 定期从 Nacos 拉取健康实例
+)
+
+// DiscoveryFactory 根据客户端名称与配置创建服务发现
+type DiscoveryFactory func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error)
+
+var discoveryRegistry = map[string]DiscoveryFactory{
+	DiscoveryEtcd: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		return discovery.NewEtcdDiscovery("/services/"+name, discovery.WithEtcdClient(cfg.EtcdClient))
+	},
+	DiscoveryStatic: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("rpc client %s: static discovery requires addrs", name)
+		}
+		return discovery.NewStaticDiscovery("/services/"+name, cfg.Addrs), nil
+	},
+	DiscoveryFile: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		if cfg.DiscoveryFile == "" {
+			return nil, fmt.Errorf("rpc client %s: file discovery requires discovery_file", name)
+		}
+		return discovery.NewFileDiscovery("/services/"+name, cfg.DiscoveryFile, cfg.DiscoveryFileInterval)
+	},
+	DiscoveryDNS: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		if cfg.DNSTarget == "" {
+			return nil, fmt.Errorf("rpc client %s: dns discovery requires dns_target", name)
+		}
+		return discovery.NewDNSDiscovery("/services/"+name, cfg.DNSTarget, discovery.WithDNSRefreshInterval(cfg.DNSRefreshInterval))
+	},
+	DiscoveryConsul: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		return discovery.NewConsulDiscovery("/services/"+name, cfg.Consul), nil
+	},
+	DiscoveryNacos: func(name string, cfg *Cfg) (discovery.ServiceDiscovery, error) {
+		return discovery.NewNacosDiscovery("/services/"+name, cfg.Nacos)
+	},
+}
+
+// RegisterDiscovery 注册自定义服务发现方式，通过 discovery 配置项选择
+func RegisterDiscovery(name string, factory DiscoveryFactory) {
+	discoveryRegistry[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+func NewRpcClientPool(name string, cfg *Cfg, opts ...Option) (r *GrpcClientPool, err error) {
 	maxActive := cfg.PoolMaxActive
 	dis, err := buildDialDiscovery(name, cfg)
 	if err != nil {
 		return
 	}
-	r, err = NewGrpcClientPool(maxActive, cfg, dis)
+	if r, err = NewGrpcClientPool(maxActive, cfg, dis, opts...); err != nil {
+		dis.Close()
+		return
+	}
+	r.ownsDiscovery = true
 	return
 }
 
 func buildDialDiscovery(name string, cfg *Cfg) (r discovery.ServiceDiscovery, err error) {
-	r, err = discovery.NewEtcdDiscovery("/services/" + name)
+	mode := strings.ToLower(strings.TrimSpace(cfg.Discovery))
+	if mode == "" {
+		mode = DiscoveryEtcd
+	}
+	factory, ok := discoveryRegistry[mode]
+	if !ok {
+		return nil, fmt.Errorf("rpc client %s: unknown discovery %q", name, cfg.Discovery)
+	}
+	if r, err = factory(name, cfg); err != nil {
+		return
+	}
+	if cfg.DiscoveryCacheFile != "" {
+		r = discovery.CacheDiscovery(cfg.DiscoveryCacheThreshold, cfg.DiscoveryCacheFile, r)
+	}
 	return
 }
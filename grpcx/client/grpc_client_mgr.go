@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -10,14 +11,21 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/liweiming-nova/common/auth"
 	"github.com/liweiming-nova/common/config"
 	"github.com/liweiming-nova/common/config/options"
 	"github.com/liweiming-nova/common/grpcx/discovery"
 	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/grpcx/tlsx"
 	"github.com/liweiming-nova/common/utils"
 	"github.com/liweiming-nova/common/xlog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 )
@@ -29,9 +37,12 @@ const (
 	FailModeFailover = "failover" // 降级重试（切换连接）
 
 	// 节点选择机制
-	SelectModeRoundRobin = "round_robin" // 轮询
-	SelectModeRandom     = "random"      // 随机
-	SelectModeScore      = "score"       // 评分
+	SelectModeRoundRobin         = "round_robin"          // 轮询
+	SelectModeRandom             = "random"               // 随机
+	SelectModeScore              = "score"                // 评分
+	SelectModeWeightedRoundRobin = "weighted_round_robin" // 平滑加权轮询（按 Score）
+	SelectModeP2C                = "p2c"                  // 两次随机选择，比较 EWMA 延迟与在途数
+	SelectModeConsistentHash     = "consistent_hash"      // 按请求 key 一致性哈希
 )
 
 type rpcConfig struct {
@@ -44,45 +55,133 @@ type Cfg struct {
 	DialTimeout        time.Duration `toml:"dial_timeout"`
 	DialFailMode       string        `toml:"fail_mode"`
 	DialSelectMode     string        `toml:"select_mode"`
-	DialConnectTimeout time.Duration `toml:"dial_timeout"`
+	DialConnectTimeout time.Duration `toml:"connect_timeout"` // 建立连接的最短超时，默认与 dial_timeout 一致
 	ServiceName        string        `toml:"service_name"`
+	// discovery
+	Discovery             string              `toml:"discovery"`               // 服务发现方式：etcd（默认）、static、file、dns、consul、nacos
+	EtcdClient            string              `toml:"etcd_client"`             // etcd 方式使用的客户端名称，对应 [etcd.<name>]，默认 [etcd]
+	Addrs                 []string            `toml:"addrs"`                   // static 方式的固定地址列表
+	DiscoveryFile         string              `toml:"discovery_file"`          // file 方式的实例文件（.json/.toml）
+	DiscoveryFileInterval time.Duration       `toml:"discovery_file_interval"` // file 方式检查文件变化的间隔，默认 5s
+	DNSTarget             string              `toml:"dns_target"`              // dns 方式的解析目标：host:port 解析 A/AAAA，否则解析 SRV
+	DNSRefreshInterval    time.Duration       `toml:"dns_refresh_interval"`    // dns 方式的解析间隔，默认 30s
+	Consul                *registry.ConsulCfg `toml:"consul"`                  // consul 方式的配置
+	Nacos                 *registry.NacosCfg  `toml:"nacos"`                   // nacos 方式的配置
+	// discovery cache
+	DiscoveryCacheFile      string `toml:"discovery_cache_file"`      // 实例列表缓存文件，非空时启用，注册中心不可用时使用缓存
+	DiscoveryCacheThreshold int    `toml:"discovery_cache_threshold"` // 默认仅在注册中心出错时使用缓存，大于 0 时实例数少于该值也使用缓存
+	// transport
+	MaxRecvMsgSize               int           `toml:"max_recv_msg_size"`               // 最大接收消息字节数，默认 4MB
+	MaxSendMsgSize               int           `toml:"max_send_msg_size"`               // 最大发送消息字节数，默认不限制
+	KeepaliveTime                time.Duration `toml:"keepalive_time"`                  // 连接空闲多久后 ping 服务端，需不小于服务端 keepalive_min_time
+	KeepaliveTimeout             time.Duration `toml:"keepalive_timeout"`               // ping 超时时间，默认 20s
+	KeepalivePermitWithoutStream bool          `toml:"keepalive_permit_without_stream"` // 无活跃流时是否 ping
 	// pool
-	PoolMaxActive int `toml:"pool_max_active"` // 最大活跃连接数
+	PoolMaxActive int           `toml:"pool_max_active"` // 每个节点的连接数，默认 1
+	DrainTimeout  time.Duration `toml:"drain_timeout"`   // 配置变更后旧连接池等待在途调用结束的最长时间
+	// timeout
+	Timeout time.Duration         `toml:"timeout"` // 单次调用超时，默认与 dial_timeout 一致
+	Methods map[string]*MethodCfg `toml:"methods"` // 按方法覆盖，key 为方法名或全限定名
 	// retry
-	RetryTimes int `toml:"retry_times"` // 重试次数（针对 local/failover）
+	RetryTimes      int           `toml:"retry_times"`       // 重试次数（针对 local/failover）
+	RetryCodes      []string      `toml:"retry_codes"`       // 可重试的状态码，默认 ["Unavailable"]
+	RetryBackoff    time.Duration `toml:"retry_backoff"`     // 重试退避基数，默认 50ms，按指数增长并带抖动
+	RetryBackoffMax time.Duration `toml:"retry_backoff_max"` // 重试退避上限，默认 1s
+	// breaker
+	Breaker *BreakerCfg `toml:"breaker"` // 熔断配置，服务级与节点级各自独立统计
+	// route
+	Route *RouteCfg `toml:"route"` // 按实例元数据路由
+	// tls
+	TLS *tlsx.Cfg `toml:"tls"`
+	// auth
+	Auth *auth.ClientCfg `toml:"auth"` // 出站调用附加的凭证
+	// interceptors
+	PropagateMetadata []string `toml:"propagate_metadata"` // 需要从 incoming 透传到下游的 metadata key（trace_id 始终透传）
+	EnableMetrics     bool     `toml:"enable_metrics"`
+	// log plugin
+	EnableLogPlugin   bool `toml:"enable_log_plugin"`
+	EnableRequestLog  bool `toml:"enable_request_log"`
+	EnableResponseLog bool `toml:"enable_response_log"`
+	EnableErrorLog    bool `toml:"enable_error_log"`
+	LogRequestArgs    bool `toml:"log_request_args"`
+	LogResponseResult bool `toml:"log_response_result"`
+	LogMaxLength      int  `toml:"log_max_length"`
 }
 
-// GrpcClientPool 是一个固定大小的 gRPC 客户端连接池
-// 使用 round-robin 算法选择客户端
+// MethodCfg 单个方法的调用配置
+type MethodCfg struct {
+	Timeout      time.Duration `toml:"timeout"`       // 单次调用超时
+	DisableRetry bool          `toml:"disable_retry"` // 禁止重试（非幂等写操作）
+}
+
+// hash 计算配置摘要，用于配置变更时判断连接池是否需要重建
+func (c *Cfg) hash() string {
+	b, _ := json.Marshal(c)
+	return utils.Md5String(string(b))
+}
+
+// GrpcClientPool 是一个基于服务发现的动态 gRPC 客户端连接池
+// 订阅 discovery 的变更，为每个节点维护独立连接，每次调用时通过选择器挑选节点
 type GrpcClientPool struct {
-	count   uint64
-	index   uint64
-	clients []*grpc.ClientConn
-	mu      sync.RWMutex
+	count     int // 每个节点的连接数
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint        // address -> endpoint
+	nodes     []*instance.ServiceInstance // 当前节点列表（与 endpoints 对应，已解码）
+	closed    bool
 
 	clientTimeout time.Duration
 	failMode      string
 	selectMode    string
 	retryTimes    int
+	retryCodes    map[codes.Code]bool
 
 	// 服务发现
-	discovery   discovery.ServiceDiscovery
-	serviceName string
-	addrIdx     uint64
+	discovery     discovery.ServiceDiscovery
+	ownsDiscovery bool // discovery 由连接池创建，Close 时一并关闭
+	serviceName   string
+	addrIdx       uint64
 
-	// 选择器
+	// 选择器与路由
 	selector Selector
+	router   *router
+
+	// TLS
+	tls *tlsx.Reloader
 
-	// Watch 支持（可选）
+	// 拦截器
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	dialOptions        []grpc.DialOption
+
+	// 熔断
+	breakerCfg *BreakerCfg
+	breaker    *breaker // 服务级熔断器
+
+	// Watch 支持
 	watchCh chan []*discovery.KVPair
+	done    chan struct{}
+
+	// 优雅关闭
+	cfg      *Cfg
+	cfgHash  string
+	inflight int64 // 在途调用数
+	draining int32 // 是否处于排空状态
 }
 
-// NewGrpcClientPool 创建一个固定大小的 gRPC 客户端池
-func NewGrpcClientPool(count int, cfg *Cfg, discovery discovery.ServiceDiscovery) (*GrpcClientPool, error) {
+// NewGrpcClientPool 创建一个动态 gRPC 客户端池，count 为每个节点的连接数
+func NewGrpcClientPool(count int, cfg *Cfg, discovery discovery.ServiceDiscovery, opts ...Option) (*GrpcClientPool, error) {
+	if discovery == nil {
+		return nil, errors.New("discovery is nil")
+	}
 	if count <= 0 {
-		count = 10
+		count = 1
 	}
 
+	// 复制一份配置再填充默认值，避免修改配置中心持有的原始对象
+	cfgHash := cfg.hash()
+	c := *cfg
+	cfg = &c
+
 	if cfg.DialTimeout <= 0 {
 		cfg.DialTimeout = 10 * time.Second
 	}
@@ -96,95 +195,334 @@ func NewGrpcClientPool(count int, cfg *Cfg, discovery discovery.ServiceDiscovery
 	if cfg.RetryTimes < 0 {
 		cfg.RetryTimes = 0
 	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = cfg.DialTimeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.RetryBackoffMax <= 0 {
+		cfg.RetryBackoffMax = defaultRetryBackoffMax
+	}
+	if cfg.RetryBackoffMax < cfg.RetryBackoff {
+		cfg.RetryBackoffMax = cfg.RetryBackoff
+	}
+	retryCodes, err := parseCodes(cfg.RetryCodes)
+	if err != nil {
+		return nil, err
+	}
 
 	pool := &GrpcClientPool{
-		count:         uint64(count),
-		clients:       make([]*grpc.ClientConn, count),
+		count:         count,
+		endpoints:     map[string]*endpoint{},
 		failMode:      cfg.DialFailMode,
 		selectMode:    cfg.DialSelectMode,
 		clientTimeout: cfg.DialTimeout,
 		discovery:     discovery,
 		serviceName:   cfg.ServiceName,
 		retryTimes:    cfg.RetryTimes,
+		retryCodes:    retryCodes,
+		done:          make(chan struct{}),
+		cfg:           cfg,
+		cfgHash:       cfgHash,
 	}
 
-	// 初始化选择器（默认轮询）
-	pool.selector = GetSelector(cfg.DialSelectMode, pool)
+	for _, opt := range opts {
+		opt(pool)
+	}
 
-	// 预创建所有客户端连接
-	for i := 0; i < count; i++ {
-		conn, err := pool.newClientConn()
-		if err != nil {
-			// 清理已创建的连接
-			pool.Close()
-			return nil, fmt.Errorf("failed to create client %d: %w", i, err)
+	if cfg.TLS.Enabled() {
+		if pool.tls, err = tlsx.NewReloader(cfg.TLS); err != nil {
+			return nil, err
 		}
-		pool.clients[i] = conn
 	}
 
+	// 初始化选择器（默认轮询）与路由，require 规则在 update 中过滤，不修改调用方传入的服务发现
+	pool.selector = GetSelector(cfg.DialSelectMode, pool)
+	pool.router = newRouter(cfg.Route)
+
+	// 初始化熔断器
+	if cfg.Breaker != nil && cfg.Breaker.Enable {
+		pool.breakerCfg = cfg.Breaker.withDefaults()
+		pool.breaker = newBreaker(pool.serviceName, pool.breakerCfg)
+	}
+
+	// 先同步一次当前节点，之后由 watch 驱动增删
+	pool.update(discovery.GetServices())
+	if _, err := pool.snapshot(); err != nil {
+		xlog.Warnf(context.Background(), "GrpcClientPool %s has no available endpoints yet", pool.serviceName)
+	}
+
+	pool.watchCh = discovery.WatchService()
+	go pool.watch()
+	go pool.keepHealthy()
+
 	return pool, nil
 }
 
-// Close 关闭所有客户端连接
-func (p *GrpcClientPool) Close() {
+// watch 消费服务发现推送的节点列表
+func (p *GrpcClientPool) watch() {
+	for {
+		select {
+		case kvPairs, ok := <-p.watchCh:
+			if !ok {
+				return
+			}
+			p.update(kvPairs)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// keepHealthy 定期检查节点连接，对失败的连接重置退避并立即重连
+func (p *GrpcClientPool) keepHealthy() {
+	ticker := time.NewTicker(reconnectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.RLock()
+			for _, ep := range p.endpoints {
+				ep.reconnect()
+			}
+			p.mu.RUnlock()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// update 根据最新节点列表新增、保留或排空节点，不满足 require 的节点不建立连接
+func (p *GrpcClientPool) update(kvPairs []*discovery.KVPair) {
+	latest := make(map[string]*discovery.KVPair, len(kvPairs))
+	for _, kv := range kvPairs {
+		if kv == nil || !p.router.filter(kv) {
+			continue
+		}
+		if addr := instance.ExtractAddress(kv.Value); addr != "" {
+			latest[addr] = kv
+		}
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+
+	// 下线节点：等待在途调用结束后关闭
+	for addr, ep := range p.endpoints {
+		if _, ok := latest[addr]; !ok {
+			delete(p.endpoints, addr)
+			go ep.drain(p.cfg.DrainTimeout)
+			xlog.Infof(context.Background(), "GrpcClientPool %s endpoint removed: %s", p.serviceName, addr)
+		}
+	}
 
-	for _, conn := range p.clients {
-		if conn != nil {
-			conn.Close()
+	// 新增节点，已有节点更新元数据
+	list := make([]*instance.ServiceInstance, 0, len(latest))
+	for addr, kv := range latest {
+		if ep, ok := p.endpoints[addr]; ok {
+			ep.setKV(kv)
+			list = append(list, ep.node)
+			continue
 		}
+		ep, err := p.newEndpoint(addr, kv)
+		if err != nil {
+			xlog.Errorf(context.Background(), "GrpcClientPool %s add endpoint %s error: %v", p.serviceName, addr, err)
+			continue
+		}
+		p.endpoints[addr] = ep
+		list = append(list, ep.node)
+		xlog.Infof(context.Background(), "GrpcClientPool %s endpoint added: %s", p.serviceName, addr)
 	}
-	p.clients = nil
+	p.nodes = list
 }
 
-// newClientConn 创建单个 grpc.ClientConn（简化版，可扩展服务发现）
-func (p *GrpcClientPool) newClientConn() (*grpc.ClientConn, error) {
-	if p.discovery == nil {
-		return nil, errors.New("discovery is nil")
+// newEndpoint 为节点创建连接
+func (p *GrpcClientPool) newEndpoint(addr string, kv *discovery.KVPair) (*endpoint, error) {
+	ep := &endpoint{addr: addr, breaker: newBreaker(p.serviceName+"@"+addr, p.breakerCfg)}
+	ep.setKV(kv)
+	for i := 0; i < p.count; i++ {
+		conn, err := p.newClientConn(addr)
+		if err != nil {
+			ep.close()
+			return nil, err
+		}
+		ep.conns = append(ep.conns, conn)
+	}
+	return ep, nil
+}
+
+// Close 停止 watch 并关闭所有节点连接
+func (p *GrpcClientPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	endpoints := p.endpoints
+	p.endpoints = map[string]*endpoint{}
+	p.nodes = nil
+	p.mu.Unlock()
+
+	if p.watchCh != nil {
+		p.discovery.RemoveWatcher(p.watchCh)
 	}
+	if p.ownsDiscovery {
+		p.discovery.Close()
+	}
+	for _, ep := range endpoints {
+		ep.close()
+	}
+	if p.tls != nil {
+		p.tls.Close()
+	}
+}
 
-	kvPairs := p.discovery.GetServices()
-	if len(kvPairs) == 0 {
-		return nil, errors.New("discovery has no services")
+// Drain 等待在途调用结束后关闭连接池，超过 timeout 则强制关闭
+func (p *GrpcClientPool) Drain(timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&p.draining, 0, 1) {
+		return
 	}
-	// 根据选择策略挑选目标（优先使用自定义选择器）
-	target := ""
-	if p.selector != nil {
-		target = p.selector.Pick(kvPairs)
+	if n := waitIdle(&p.inflight, timeout); n > 0 {
+		xlog.Warnf(context.Background(), "Drain pool for service %s timeout, %d calls still in flight", p.serviceName, n)
 	}
-	if target == "" { // 兜底使用内置逻辑
-		target = p.pickTarget(kvPairs)
+	p.Close()
+}
+
+// newClientConn 为指定地址创建 grpc.ClientConn（非阻塞，失败由 gRPC 自动重连）
+func (p *GrpcClientPool) newClientConn(target string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if p.tls != nil {
+		creds = credentials.NewTLS(p.tls.ClientConfig())
+	}
+	connectTimeout := p.cfg.DialConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = p.clientTimeout
 	}
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: connectTimeout,
+		}),
 	}
-
-	// 使用带超时的 DialContext
-	ctxDial, cancelDial := context.WithTimeout(context.Background(), p.clientTimeout)
-	defer cancelDial()
-	conn, err := grpc.DialContext(ctxDial, target, opts...)
+	var callOpts []grpc.CallOption
+	if p.cfg.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(p.cfg.MaxRecvMsgSize))
+	}
+	if p.cfg.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(p.cfg.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	if p.cfg.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                p.cfg.KeepaliveTime,
+			Timeout:             p.cfg.KeepaliveTimeout,
+			PermitWithoutStream: p.cfg.KeepalivePermitWithoutStream,
+		}))
+	}
+	unary, stream := p.buildInterceptors()
+	opts = append(opts,
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	)
+	opts = append(opts, p.dialOptions...)
+
+	conn, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client for %s: %w", target, err)
 	}
-
-	// 使用阻塞拨号已由 DialContext 控制超时，若失败会返回 err
+	// 立即建立连接，避免首次调用时才握手
+	conn.Connect()
 
 	return conn, nil
 }
 
-// pickTarget 根据 selectMode 选择目标地址
-func (p *GrpcClientPool) pickTarget(kvPairs []*discovery.KVPair) string {
-	n := len(kvPairs)
-	if n == 0 {
-		return ""
+// snapshot 返回当前可用节点列表：排除熔断中的节点，并优先排除连接处于失败状态的节点
+func (p *GrpcClientPool) snapshot() ([]*instance.ServiceInstance, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.nodes) == 0 {
+		return nil, fmt.Errorf("no available endpoint for service %s", p.serviceName)
+	}
+
+	list := make([]*instance.ServiceInstance, 0, len(p.nodes))
+	closed := make([]*instance.ServiceInstance, 0, len(p.nodes)) // 未熔断的节点
+	for _, node := range p.nodes {
+		ep := p.endpoints[node.Address]
+		if ep == nil || !ep.breaker.ready() {
+			continue
+		}
+		closed = append(closed, node)
+		if ep.available() {
+			list = append(list, node)
+		}
+	}
+	if len(closed) == 0 {
+		return nil, &CircuitOpenError{Service: p.serviceName}
+	}
+	// 全部节点连接都不可用时退化为未熔断的节点，交给 gRPC 自身的重连机制
+	if len(list) == 0 {
+		list = closed
+	}
+	return list, nil
+}
+
+// pick 按路由规则过滤节点后由选择器挑选本次调用的节点；
+// 返回的节点已占用一个在途计数（在读锁内占用，下线排空不会在此之后关闭连接），调用方用完后需 release；
+// 同时返回选中的实例，反馈给选择器时使用，避免在锁外读取会被元数据更新替换的 endpoint.node
+func (p *GrpcClientPool) pick(ctx context.Context) (*endpoint, *instance.ServiceInstance, error) {
+	nodes, err := p.snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	if nodes = p.router.route(ctx, nodes); len(nodes) == 0 {
+		return nil, nil, fmt.Errorf("no endpoint of service %s matches route", p.serviceName)
+	}
+	// 根据选择策略挑选目标（优先使用自定义选择器）
+	var node *instance.ServiceInstance
+	if p.selector != nil {
+		node = p.selector.Pick(ctx, nodes)
+	}
+	if node == nil { // 兜底使用内置逻辑
+		node = p.pickTarget(nodes)
+	}
+
+	p.mu.RLock()
+	ep := p.endpoints[node.Address]
+	if ep != nil {
+		ep.acquire()
+	}
+	p.mu.RUnlock()
+	if ep == nil {
+		err = fmt.Errorf("endpoint %s of service %s not found", node.Address, p.serviceName)
+		p.feedback(node, DoneInfo{Err: err})
+		return nil, nil, err
+	}
+	return ep, node, nil
+}
+
+// feedback 向选择器反馈 pick 选中节点的调用结果
+func (p *GrpcClientPool) feedback(node *instance.ServiceInstance, info DoneInfo) {
+	if p.selector != nil && node != nil {
+		p.selector.Done(node, info)
 	}
+}
+
+// pickTarget 根据 selectMode 选择目标节点
+func (p *GrpcClientPool) pickTarget(nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	n := len(nodes)
 	mode := strings.ToLower(strings.TrimSpace(p.selectMode))
 	switch mode {
 	case SelectModeRandom:
-		idx := rand.Intn(n)
-		return instance.ExtractAddress(kvPairs[idx].Value)
+		return nodes[rand.Intn(n)]
 	case SelectModeScore:
 		// 简单评分选择：抽样 3 个，选分最高；若不足 3 个则全量找最高
 		bestIdx, bestScore := 0, float64(0)
@@ -197,89 +535,126 @@ func (p *GrpcClientPool) pickTarget(kvPairs []*discovery.KVPair) string {
 			if n > sample {
 				idx = rand.Intn(n)
 			}
-			score := instance.ExtractScore(kvPairs[idx].Value)
-			if score > bestScore {
+			if score := nodes[idx].Weight(); score > bestScore {
 				bestScore = score
 				bestIdx = idx
 			}
 		}
-		return instance.ExtractAddress(kvPairs[bestIdx].Value)
+		return nodes[bestIdx]
 	case SelectModeRoundRobin:
 		fallthrough
 	default:
 		idx := int(atomic.AddUint64(&p.addrIdx, 1) % uint64(n))
-		return instance.ExtractAddress(kvPairs[idx].Value)
+		return nodes[idx]
 	}
 }
 
-// Get 返回一个客户端（不移除，不关闭，线程安全）
-// 使用 round-robin 策略
+// Get 按选择器挑选一个节点并返回其连接（不移除，不关闭，线程安全）
 func (p *GrpcClientPool) Get() *grpc.ClientConn {
-	if p == nil || len(p.clients) == 0 {
+	if p == nil {
 		return nil
 	}
+	ep, node, err := p.pick(context.Background())
+	if err != nil {
+		return nil
+	}
+	defer ep.release()
+	p.feedback(node, DoneInfo{})
+	return ep.conn()
+}
 
-	i := atomic.AddUint64(&p.index, 1)
-	picked := int(i % p.count)
-	return p.clients[picked]
+// fullMethod 规范化方法名：
+// - 若已是全限定名（以 / 开头），直接使用
+// - 否则自动拼接为 /<ServiceName>/<Method>
+func (p *GrpcClientPool) fullMethod(method string) (string, error) {
+	if strings.HasPrefix(method, "/") {
+		return method, nil
+	}
+	svc := strings.TrimSpace(p.serviceName)
+	if svc == "" {
+		return "", fmt.Errorf("service name is empty, cannot build full method name for %q", method)
+	}
+	// 确保服务名不带前导斜杠
+	svc = strings.TrimPrefix(svc, "/")
+	return "/" + svc + "/" + strings.TrimPrefix(method, "/"), nil
 }
 
 // Call 通过连接池调用 gRPC 方法
-func (p *GrpcClientPool) Call(ctx context.Context, method string, req proto.Message, resp proto.Message) error {
-	client := p.Get()
-	if client == nil {
-		return fmt.Errorf("no available client in pool")
-	}
-
-	// 规范化方法名：
-	// - 若已是全限定名（以 / 开头），直接使用
-	// - 否则自动拼接为 /<ServiceName>/<Method>
-	normalized := method
-	if !strings.HasPrefix(method, "/") {
-		svc := strings.TrimSpace(p.serviceName)
-		if svc == "" {
-			return fmt.Errorf("service name is empty, cannot build full method name for %q", method)
-		}
-		// 确保服务名不带前导斜杠
-		svc = strings.TrimPrefix(svc, "/")
-		normalized = "/" + svc + "/" + strings.TrimPrefix(method, "/")
+func (p *GrpcClientPool) Call(ctx context.Context, method string, req proto.Message, resp proto.Message) (err error) {
+	atomic.AddInt64(&p.inflight, 1)
+	defer atomic.AddInt64(&p.inflight, -1)
+
+	normalized, err := p.fullMethod(method)
+	if err != nil {
+		return err
 	}
 
-	// 注入 trace_id 到 outgoing metadata
+	// 注入 trace_id 到 outgoing metadata（在重试循环外注入，保证多次重试使用同一 trace_id）
 	ctx = withTraceID(ctx)
 
-	// 重试策略
-	switch strings.ToLower(strings.TrimSpace(p.failMode)) {
-	case FailModeNothing:
-		return client.Invoke(ctx, normalized, req, resp)
-	case FailModeLocal: // 原地重试：同一连接重试
-		var lastErr error
-		for i := 0; i <= p.retryTimes; i++ {
-			if err := client.Invoke(ctx, normalized, req, resp); err != nil {
-				lastErr = err
-				continue
+	_, retryTimes := p.methodPolicy(normalized)
+
+	// 重试策略：local 固定同一节点，failover 每次重新挑选节点
+	mode := strings.ToLower(strings.TrimSpace(p.failMode))
+	if mode != FailModeLocal && mode != FailModeFailover {
+		retryTimes = 0
+	}
+
+	// 每次 pick 对应一次 Done，反馈该节点上最后一次调用的结果
+	var ep *endpoint
+	var node *instance.ServiceInstance
+	var info DoneInfo
+	defer func() {
+		p.feedback(node, info)
+		if ep != nil {
+			ep.release()
+		}
+	}()
+
+	var lastErr error
+	for i := 0; i <= retryTimes; i++ {
+		if i > 0 {
+			if !p.retryable(ctx, lastErr) {
+				break
+			}
+			if err := p.backoff(ctx, i); err != nil {
+				break
 			}
-			return nil
 		}
-		return lastErr
-	case FailModeFailover: // 降级重试：更换节点
-		var lastErr error
-		for i := 0; i <= p.retryTimes; i++ {
-			cc := p.Get()
-			if cc == nil {
-				lastErr = fmt.Errorf("no available client in pool")
-				continue
+		// 先挑选节点，服务级熔断的半开探测名额只在真正发起调用前占用，
+		// 挑选失败或节点熔断时不会占用名额导致熔断器卡在半开状态
+		if !p.breaker.ready() {
+			return &CircuitOpenError{Service: p.serviceName}
+		}
+		if ep == nil || mode == FailModeFailover {
+			p.feedback(node, info)
+			if ep != nil {
+				ep.release()
 			}
-			if err := cc.Invoke(ctx, normalized, req, resp); err != nil {
-				lastErr = err
-				continue
+			node, info = nil, DoneInfo{}
+			if ep, node, err = p.pick(ctx); err != nil {
+				return err
 			}
+		}
+		if !p.breaker.allow() {
+			info = DoneInfo{Err: &CircuitOpenError{Service: p.serviceName}}
+			return info.Err
+		}
+		if !ep.breaker.allow() {
+			p.breaker.release()
+			info = DoneInfo{Err: &CircuitOpenError{Service: p.serviceName, Endpoint: ep.addr}}
+			return info.Err
+		}
+		start := time.Now()
+		lastErr = ep.invoke(ctx, normalized, req, resp)
+		info = DoneInfo{Err: lastErr, Duration: time.Since(start)}
+		p.breaker.record(lastErr)
+		ep.breaker.record(lastErr)
+		if lastErr == nil {
 			return nil
 		}
-		return lastErr
-	default:
-		return client.Invoke(ctx, normalized, req, resp)
 	}
+	return lastErr
 }
 
 // withTraceID 确保在 outgoing metadata 中携带 trace_id
@@ -303,10 +678,19 @@ func withTraceID(ctx context.Context) context.Context {
 	return metadata.AppendToOutgoingContext(ctx, xlog.TraceId, traceID)
 }
 
+const (
+	defaultDrainTimeout    = 30 * time.Second
+	reconnectInterval      = 5 * time.Second
+	defaultRetryBackoff    = 50 * time.Millisecond
+	defaultRetryBackoffMax = time.Second
+)
+
 var (
 	once  sync.Once
 	lock  sync.RWMutex
 	pools map[string]*GrpcClientPool
+	// poolsGen 每次配置变更时递增，用于识别按旧配置创建的连接池
+	poolsGen uint64
 )
 
 func init() {
@@ -322,6 +706,15 @@ func Call(ctx context.Context, name string, method string, req proto.Message, re
 	return
 }
 
+func NewStream(ctx context.Context, name string, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (r grpc.ClientStream, err error) {
+	var cli *GrpcClientPool
+	cli, err = SafeClient(name)
+	if err == nil {
+		r, err = cli.NewStream(ctx, desc, method, opts...)
+	}
+	return
+}
+
 func SafeClient(name string) (r *GrpcClientPool, err error) {
 	r, err = SafePool(name)
 	if err != nil {
@@ -343,17 +736,39 @@ func getPool(name string) (r *GrpcClientPool, err error) {
 	}
 	return
 }
+
+// addPool 在锁外创建连接池（包含服务发现的网络请求），写入时再检查：
+// 其他 goroutine 已写入则关闭自己创建的连接池；期间配置发生变更则按新配置重建
 func addPool(name string) (r *GrpcClientPool, err error) {
-	var cfg *Cfg
-	if cfg, err = loadCfg(name); err != nil {
-		return
-	}
-	r, err = NewRpcClientPool(name, cfg)
+	for {
+		lock.RLock()
+		gen := poolsGen
+		lock.RUnlock()
+
+		var cfg *Cfg
+		if cfg, err = loadCfg(name); err != nil {
+			return
+		}
+		var pool *GrpcClientPool
+		if pool, err = NewRpcClientPool(name, cfg, getClientOptions(name)...); err != nil {
+			return
+		}
 
-	lock.Lock()
-	pools[name] = r
-	lock.Unlock()
-	return
+		lock.Lock()
+		if r = pools[name]; r != nil {
+			lock.Unlock()
+			pool.Close()
+			return
+		}
+		if gen != poolsGen {
+			lock.Unlock()
+			pool.Close()
+			continue
+		}
+		pools[name] = pool
+		lock.Unlock()
+		return pool, nil
+	}
 }
 
 func loadCfg(name string) (r *Cfg, err error) {
@@ -373,17 +788,26 @@ func loadCfgs() (r map[string]*Cfg, err error) {
 
 	once.Do(func() {
 		config.Get(&rpcConfig{}, options.WithOpOnChangeFn(func(cfg interface{}) {
+			var cfgs map[string]*Cfg
+			if c, ok := cfg.(*rpcConfig); ok && c.Rpc != nil {
+				cfgs = c.Rpc.Cfgs
+			}
 			lock.Lock()
 			defer lock.Unlock()
-			// 关闭所有旧连接池
+			poolsGen++
+			// 仅重建配置发生变化的连接池，已删除的配置对应连接池直接关闭
 			for name, pool := range pools {
-				if pool != nil {
-					go pool.Close() // 异步关闭，避免阻塞配置更新
-					xlog.Infof(context.Background(), "Closed old pool for service: %s", name)
+				if pool == nil {
+					delete(pools, name)
+					continue
 				}
+				if c := cfgs[name]; c != nil && c.hash() == pool.cfgHash {
+					continue
+				}
+				delete(pools, name)
+				go pool.Drain(pool.cfg.DrainTimeout) // 异步排空，避免阻塞配置更新
+				xlog.Infof(context.Background(), "Draining old pool for service: %s", name)
 			}
-
-			pools = map[string]*GrpcClientPool{}
 		}))
 	})
 
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/auth"
+	"github.com/liweiming-nova/common/grpcx/logging"
+	"github.com/liweiming-nova/common/utils/metrics"
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Option 连接池选项
+type Option func(p *GrpcClientPool)
+
+// WithClientInterceptors 追加自定义 unary 客户端拦截器
+func WithClientInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(p *GrpcClientPool) {
+		p.unaryInterceptors = append(p.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamClientInterceptors 追加自定义 stream 客户端拦截器
+func WithStreamClientInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(p *GrpcClientPool) {
+		p.streamInterceptors = append(p.streamInterceptors, interceptors...)
+	}
+}
+
+// WithDialOptions 追加自定义拨号选项
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(p *GrpcClientPool) {
+		p.dialOptions = append(p.dialOptions, opts...)
+	}
+}
+
+var (
+	optionsLock   sync.RWMutex
+	clientOptions = map[string][]Option{}
+)
+
+// RegisterClientOptions 为指定名称的客户端注册选项，需在首次调用该客户端前注册
+func RegisterClientOptions(name string, opts ...Option) {
+	optionsLock.Lock()
+	defer optionsLock.Unlock()
+	clientOptions[name] = append(clientOptions[name], opts...)
+}
+
+// RegisterClientInterceptors 为指定名称的客户端注册 unary 拦截器
+func RegisterClientInterceptors(name string, interceptors ...grpc.UnaryClientInterceptor) {
+	RegisterClientOptions(name, WithClientInterceptors(interceptors...))
+}
+
+// RegisterStreamClientInterceptors 为指定名称的客户端注册 stream 拦截器
+func RegisterStreamClientInterceptors(name string, interceptors ...grpc.StreamClientInterceptor) {
+	RegisterClientOptions(name, WithStreamClientInterceptors(interceptors...))
+}
+
+func getClientOptions(name string) []Option {
+	optionsLock.RLock()
+	defer optionsLock.RUnlock()
+	return append([]Option(nil), clientOptions[name]...)
+}
+
+// buildInterceptors 组装拦截器链：元数据透传 -> 凭证 -> 日志 -> 指标 -> 自定义 -> 超时
+func (p *GrpcClientPool) buildInterceptors() (unary []grpc.UnaryClientInterceptor, stream []grpc.StreamClientInterceptor) {
+	propagate := &propagateInterceptor{keys: p.cfg.PropagateMetadata}
+	unary = append(unary, propagate.UnaryClientInterceptor)
+	stream = append(stream, propagate.StreamClientInterceptor)
+
+	if p.cfg.Auth != nil && p.cfg.Auth.Type != "" {
+		credentials := auth.NewClientInterceptor(p.cfg.Auth)
+		unary = append(unary, credentials.UnaryClientInterceptor)
+		stream = append(stream, credentials.StreamClientInterceptor)
+	}
+
+	if p.cfg.EnableLogPlugin {
+		logInterceptor := NewLogInterceptor(
+			WithEnableRequestLog(p.cfg.EnableRequestLog),
+			WithEnableResponseLog(p.cfg.EnableResponseLog),
+			WithEnableErrorLog(p.cfg.EnableErrorLog),
+			WithLogRequestArgs(p.cfg.LogRequestArgs),
+			WithLogResponseResult(p.cfg.LogResponseResult),
+			WithMaxLogLength(p.cfg.LogMaxLength),
+		)
+		unary = append(unary, logInterceptor.UnaryClientInterceptor)
+	}
+	if p.cfg.EnableMetrics {
+		unary = append(unary, MetricsInterceptor)
+	}
+
+	unary = append(unary, p.unaryInterceptors...)
+	stream = append(stream, p.streamInterceptors...)
+
+	unary = append(unary, p.timeoutInterceptor)
+	return
+}
+
+// timeoutInterceptor 按方法配置为每次调用注入超时，上游 deadline 更早时以上游为准
+func (p *GrpcClientPool) timeoutInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if timeout, _ := p.methodPolicy(method); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// propagateInterceptor 将 trace_id 及配置的 incoming metadata 透传到下游
+type propagateInterceptor struct {
+	keys []string
+}
+
+func (i *propagateInterceptor) outgoing(ctx context.Context) context.Context {
+	ctx = withTraceID(ctx)
+	if len(i.keys) == 0 {
+		return ctx
+	}
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	out, _ := metadata.FromOutgoingContext(ctx)
+	var kv []string
+	for _, key := range i.keys {
+		key = strings.ToLower(key)
+		if len(out.Get(key)) > 0 {
+			continue
+		}
+		for _, v := range in.Get(key) {
+			kv = append(kv, key, v)
+		}
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func (i *propagateInterceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(i.outgoing(ctx), method, req, reply, cc, opts...)
+}
+
+func (i *propagateInterceptor) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(i.outgoing(ctx), desc, cc, method, opts...)
+}
+
+// MetricsInterceptor 统计客户端调用次数与耗时
+func MetricsInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	code := status.Code(err).String()
+	metrics.GetCounter("grpc_client_requests_total", "method", method, "code", code).Inc()
+	metrics.GetSummary("grpc_client_request_duration_seconds", "method", method).Observe(time.Since(start))
+	return err
+}
+
+// LogInterceptor 客户端请求/响应日志拦截器，与 server.LogInterceptor 共用 logging.Options
+type LogInterceptor struct {
+	logging.Options
+}
+
+// NewLogInterceptor 创建客户端日志拦截器
+func NewLogInterceptor(options ...LogInterceptorOption) *LogInterceptor {
+	return &LogInterceptor{Options: logging.NewOptions(options...)}
+}
+
+type LogInterceptorOption = logging.Option
+
+var (
+	WithEnableRequestLog  = logging.WithEnableRequestLog
+	WithEnableResponseLog = logging.WithEnableResponseLog
+	WithEnableErrorLog    = logging.WithEnableErrorLog
+	WithLogRequestArgs    = logging.WithLogRequestArgs
+	WithLogResponseResult = logging.WithLogResponseResult
+	WithMaxLogLength      = logging.WithMaxLogLength
+)
+
+// UnaryClientInterceptor 实现 gRPC Unary 客户端拦截器
+func (p *LogInterceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	startTime := time.Now()
+
+	if p.EnableRequestLog {
+		logMsg := fmt.Sprintf("gRPC Client Request - Target: %s, Method: %s", cc.Target(), method)
+		if p.LogRequestArgs && req != nil {
+			logMsg += fmt.Sprintf(", Args: %s", p.Marshal(req))
+		}
+		xlog.Info(ctx, logMsg)
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	logMsg := fmt.Sprintf("gRPC Client Response - Target: %s, Method: %s, Duration: %v", cc.Target(), method, time.Since(startTime))
+	if err != nil {
+		if p.EnableErrorLog {
+			xlog.Errorf(ctx, "%s, Error: %v", logMsg, err)
+		}
+	} else if p.EnableResponseLog {
+		if p.LogResponseResult && reply != nil {
+			logMsg += fmt.Sprintf(", Result: %s", p.Marshal(reply))
+		}
+		xlog.Info(ctx, logMsg)
+	}
+	return err
+}
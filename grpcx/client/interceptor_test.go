@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/auth"
+	"github.com/liweiming-nova/common/utils/metrics"
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	requests := metrics.GetCounter("grpc_client_requests_total", "method", "/test.Echo/Call", "code", "OK")
+	before := requests.Value()
+
+	var order []string
+	custom := func(name string) grpc.UnaryClientInterceptor {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			order = append(order, name)
+			md, _ := metadata.FromOutgoingContext(ctx)
+			// 透传与凭证在自定义拦截器之前完成
+			if md.Get("x-tenant")[0] != "t1" || md.Get(xlog.TraceId)[0] != "trace-1" || md.Get(auth.HeaderAPIKey)[0] != "key" {
+				t.Errorf("%s: outgoing metadata not prepared: %v", name, md)
+			}
+			// 指标在外层，调用结束后才计数
+			if requests.Value() != before {
+				t.Errorf("%s: metrics interceptor should wrap custom interceptors", name)
+			}
+			// 超时在最内层注入
+			if _, ok := ctx.Deadline(); ok {
+				t.Errorf("%s: timeout should be applied after custom interceptors", name)
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+
+	var received metadata.MD
+	var deadline bool
+	pool := newTestPool(t, &Cfg{
+		PropagateMetadata: []string{"X-Tenant"},
+		Auth:              &auth.ClientCfg{Type: auth.TypeAPIKey, APIKey: "key"},
+		EnableMetrics:     true,
+		EnableLogPlugin:   true,
+		Methods:           map[string]*MethodCfg{"Call": {Timeout: time.Second}},
+	}, &testService{unary: func(ctx context.Context) error {
+		received, _ = metadata.FromIncomingContext(ctx)
+		_, deadline = ctx.Deadline()
+		return nil
+	}}, WithClientInterceptors(custom("first"), custom("second")))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant", "t1", xlog.TraceId, "trace-1", "x-private", "secret"))
+	if err := pool.Call(ctx, "Call", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("custom interceptors out of order: %v", order)
+	}
+	if received.Get("x-tenant")[0] != "t1" || received.Get(xlog.TraceId)[0] != "trace-1" || received.Get(auth.HeaderAPIKey)[0] != "key" {
+		t.Fatalf("metadata not propagated: %v", received)
+	}
+	if len(received.Get("x-private")) != 0 {
+		t.Fatalf("unlisted metadata should not be propagated: %v", received)
+	}
+	if !deadline {
+		t.Fatal("method timeout not applied")
+	}
+	if requests.Value() != before+1 {
+		t.Fatal("metrics not recorded")
+	}
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// testService 测试服务，unary 与 stream 的行为由用例指定
+type testService struct {
+	unary  func(ctx context.Context) error
+	stream func(stream grpc.ServerStream) error
+}
+
+var testServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Call",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &emptypb.Empty{}, srv.(*testService).unary(ctx)
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Echo/Call"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return srv.(*testService).stream(stream)
+		},
+	}},
+}
+
+// newTestPool 启动 bufconn 上的测试服务，返回只包含该节点的连接池
+func newTestPool(t *testing.T, cfg *Cfg, svc *testService, opts ...Option) *GrpcClientPool {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	s.RegisterService(&testServiceDesc, svc)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	d := discovery.NewMemoryDiscovery("/services/test.Echo")
+	d.Add("/services/test.Echo/bufnet", instanceValue(t, "passthrough:///bufnet", nil))
+	opts = append(opts, WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	})))
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "test.Echo"
+	}
+	pool, err := NewGrpcClientPool(1, cfg, d, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// waitFor 轮询直到条件成立或超时
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func instanceValue(t *testing.T, addr string, metadata map[string]string) string {
+	t.Helper()
+	b, err := instance.Encode(instance.New(addr, metadata, time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func (p *GrpcClientPool) endpoint(addr string) *endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.endpoints[addr]
+}
+
+func TestPoolUpdate(t *testing.T) {
+	d := discovery.NewMemoryDiscovery("/services/test")
+	d.Add("/services/test/127.0.0.1:1", instanceValue(t, "127.0.0.1:1", nil))
+	pool, err := NewGrpcClientPool(1, &Cfg{ServiceName: "test", DrainTimeout: 5 * time.Second}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if pool.endpoint("127.0.0.1:1") == nil {
+		t.Fatal("initial endpoint not added")
+	}
+
+	// 新增节点
+	d.Add("/services/test/127.0.0.1:2", instanceValue(t, "127.0.0.1:2", nil))
+	waitFor(t, "endpoint added", func() bool { return pool.endpoint("127.0.0.1:2") != nil })
+
+	// 在途调用占用节点时下线，排空需等到调用结束才关闭连接
+	ep, _, err := pool.pick(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := "127.0.0.1:1"
+	if ep.addr == other {
+		other = "127.0.0.1:2"
+	}
+	d.Remove("/services/test/" + ep.addr)
+	waitFor(t, "endpoint removed", func() bool { return pool.endpoint(ep.addr) == nil })
+	time.Sleep(200 * time.Millisecond)
+	if state := ep.conns[0].GetState(); state == connectivity.Shutdown {
+		t.Fatal("removed endpoint closed while a call is still in flight")
+	}
+	ep.release()
+	waitFor(t, "drained endpoint closed", func() bool { return ep.conns[0].GetState() == connectivity.Shutdown })
+
+	if pool.endpoint(other) == nil {
+		t.Fatal("remaining endpoint should be kept")
+	}
+	nodes, err := pool.snapshot()
+	if err != nil || len(nodes) != 1 || nodes[0].Address != other {
+		t.Fatalf("unexpected nodes after removal: %v %v", nodes, err)
+	}
+}
+
+func TestPoolRequireFilter(t *testing.T) {
+	d := discovery.NewMemoryDiscovery("/services/test")
+	d.Add("/services/test/127.0.0.1:1", instanceValue(t, "127.0.0.1:1", map[string]string{"version": "v1"}))
+	d.Add("/services/test/127.0.0.1:2", instanceValue(t, "127.0.0.1:2", map[string]string{"version": "v2"}))
+	pool, err := NewGrpcClientPool(1, &Cfg{ServiceName: "test", Route: &RouteCfg{Require: map[string]string{"version": "v2"}}}, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if pool.endpoint("127.0.0.1:1") != nil || pool.endpoint("127.0.0.1:2") == nil {
+		t.Fatal("only endpoints matching require should be connected")
+	}
+	// 过滤在连接池内完成，共享的服务发现仍返回全部节点
+	if n := len(d.GetServices()); n != 2 {
+		t.Fatalf("discovery should not be filtered, got %d services", n)
+	}
+
+	d.Add("/services/test/127.0.0.1:3", instanceValue(t, "127.0.0.1:3", map[string]string{"version": "v2"}))
+	waitFor(t, "matching endpoint added", func() bool { return pool.endpoint("127.0.0.1:3") != nil })
+	if pool.endpoint("127.0.0.1:1") != nil {
+		t.Fatal("endpoint not matching require should stay filtered after update")
+	}
+}
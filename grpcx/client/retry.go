@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultRetryCodes 未配置 retry_codes 时可重试的状态码
+var defaultRetryCodes = []codes.Code{codes.Unavailable}
+
+// parseCodes 解析状态码名称，大小写与下划线不敏感，如 Unavailable、DEADLINE_EXCEEDED
+func parseCodes(names []string) (map[codes.Code]bool, error) {
+	r := map[codes.Code]bool{}
+	if len(names) == 0 {
+		for _, c := range defaultRetryCodes {
+			r[c] = true
+		}
+		return r, nil
+	}
+	for _, name := range names {
+		c, ok := lookupCode(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown grpc status code %q in retry_codes", name)
+		}
+		r[c] = true
+	}
+	return r, nil
+}
+
+func lookupCode(name string) (codes.Code, bool) {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), "_", ""))
+	}
+	want := normalize(name)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if normalize(c.String()) == want {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// methodPolicy 返回方法的单次超时与重试次数，方法配置优先匹配全限定名，其次匹配方法名
+func (p *GrpcClientPool) methodPolicy(fullMethod string) (timeout time.Duration, retryTimes int) {
+	timeout, retryTimes = p.cfg.Timeout, p.retryTimes
+	if len(p.cfg.Methods) == 0 {
+		return
+	}
+	m := p.cfg.Methods[fullMethod]
+	if m == nil {
+		m = p.cfg.Methods[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+	}
+	if m == nil {
+		return
+	}
+	if m.Timeout > 0 {
+		timeout = m.Timeout
+	}
+	if m.DisableRetry {
+		retryTimes = 0
+	}
+	return
+}
+
+// retryable 判断错误是否允许重试：上游 context 已结束时不再重试
+func (p *GrpcClientPool) retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	return p.retryCodes[s.Code()]
+}
+
+// errNoTimeLeft 剩余时间不足以完成下一次退避
+var errNoTimeLeft = errors.New("not enough time left before deadline to retry")
+
+// backoff 指数退避并带抖动，attempt 从 1 开始；剩余 deadline 不足时直接放弃
+func (p *GrpcClientPool) backoff(ctx context.Context, attempt int) error {
+	d := p.cfg.RetryBackoff << uint(attempt-1)
+	if d <= 0 || d > p.cfg.RetryBackoffMax {
+		d = p.cfg.RetryBackoffMax
+	}
+	// 抖动：在 [d/2, d) 之间随机
+	if half := int64(d / 2); half > 0 {
+		d = time.Duration(half + rand.Int63n(half))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return errNoTimeLeft
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestParseCodes(t *testing.T) {
+	cases := []struct {
+		names   []string
+		want    []codes.Code
+		wantErr bool
+	}{
+		{names: nil, want: []codes.Code{codes.Unavailable}},
+		{names: []string{"Unavailable", "DEADLINE_EXCEEDED", " resource_exhausted "}, want: []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted}},
+		{names: []string{"internal"}, want: []codes.Code{codes.Internal}},
+		{names: []string{"NotACode"}, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := parseCodes(c.names)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected error", c.names)
+			}
+			continue
+		}
+		if err != nil || len(got) != len(c.want) {
+			t.Errorf("%v: got %v %v", c.names, got, err)
+			continue
+		}
+		for _, code := range c.want {
+			if !got[code] {
+				t.Errorf("%v: missing %s", c.names, code)
+			}
+		}
+	}
+}
+
+func TestMethodPolicy(t *testing.T) {
+	p := &GrpcClientPool{retryTimes: 3, cfg: &Cfg{Timeout: time.Second, Methods: map[string]*MethodCfg{
+		"/user.User/GetUser": {Timeout: 200 * time.Millisecond},
+		"CreateUser":         {DisableRetry: true},
+		"/user.User/List":    {Timeout: 2 * time.Second, DisableRetry: true},
+		"List":               {Timeout: 5 * time.Second},
+	}}}
+	cases := []struct {
+		method  string
+		timeout time.Duration
+		retry   int
+	}{
+		{"/user.User/GetUser", 200 * time.Millisecond, 3},
+		{"/user.User/CreateUser", time.Second, 0},
+		{"/user.User/List", 2 * time.Second, 0}, // 全限定名优先于方法名
+		{"/other.Other/List", 5 * time.Second, 3},
+		{"/user.User/Unknown", time.Second, 3},
+	}
+	for _, c := range cases {
+		timeout, retry := p.methodPolicy(c.method)
+		if timeout != c.timeout || retry != c.retry {
+			t.Errorf("%s: got (%s, %d), want (%s, %d)", c.method, timeout, retry, c.timeout, c.retry)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	p := &GrpcClientPool{retryCodes: map[codes.Code]bool{codes.Unavailable: true}}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	cases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"nil error", context.Background(), nil, false},
+		{"retryable code", context.Background(), status.Error(codes.Unavailable, "down"), true},
+		{"other code", context.Background(), status.Error(codes.InvalidArgument, "bad"), false},
+		{"non status error", context.Background(), errors.New("plain"), false},
+		{"caller cancelled", cancelled, status.Error(codes.Unavailable, "down"), false},
+	}
+	for _, c := range cases {
+		if got := p.retryable(c.ctx, c.err); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := &GrpcClientPool{cfg: &Cfg{RetryBackoff: 20 * time.Millisecond, RetryBackoffMax: 40 * time.Millisecond}}
+	cases := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 10 * time.Millisecond, 20 * time.Millisecond},
+		{2, 20 * time.Millisecond, 40 * time.Millisecond},
+		{5, 20 * time.Millisecond, 40 * time.Millisecond}, // 超过上限时取上限
+	}
+	for _, c := range cases {
+		start := time.Now()
+		if err := p.backoff(context.Background(), c.attempt); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < c.min || d > c.max+50*time.Millisecond {
+			t.Errorf("attempt %d: waited %s, want [%s, %s)", c.attempt, d, c.min, c.max)
+		}
+	}
+
+	// 剩余时间不足一次退避时立即放弃
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := p.backoff(ctx, 2); err != errNoTimeLeft {
+		t.Fatalf("expected errNoTimeLeft, got %v", err)
+	}
+	cancelled, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if err := p.backoff(cancelled, 1); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// failingService 前 failures 次调用返回 err，之后成功
+func failingService(failures int32, err error, calls *int32) *testService {
+	return &testService{unary: func(ctx context.Context) error {
+		if atomic.AddInt32(calls, 1) <= failures {
+			return err
+		}
+		return nil
+	}}
+}
+
+func TestCallRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	cases := []struct {
+		name      string
+		cfg       *Cfg
+		failures  int32
+		err       error
+		wantCalls int32
+		wantCode  codes.Code
+	}{
+		{"retry until success", &Cfg{DialFailMode: FailModeFailover, RetryTimes: 3}, 2, unavailable, 3, codes.OK},
+		{"retries exhausted", &Cfg{DialFailMode: FailModeLocal, RetryTimes: 2}, 5, unavailable, 3, codes.Unavailable},
+		{"code not retryable", &Cfg{DialFailMode: FailModeFailover, RetryTimes: 3}, 1, status.Error(codes.Internal, "boom"), 1, codes.Internal},
+		{"configured codes", &Cfg{DialFailMode: FailModeFailover, RetryTimes: 3, RetryCodes: []string{"internal"}}, 1, status.Error(codes.Internal, "boom"), 2, codes.OK},
+		{"fail mode nothing", &Cfg{RetryTimes: 3}, 1, unavailable, 1, codes.Unavailable},
+		{"retry disabled for method", &Cfg{DialFailMode: FailModeFailover, RetryTimes: 3, Methods: map[string]*MethodCfg{"Call": {DisableRetry: true}}}, 1, unavailable, 1, codes.Unavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int32
+			c.cfg.RetryBackoff = time.Millisecond
+			pool := newTestPool(t, c.cfg, failingService(c.failures, c.err, &calls))
+			err := pool.Call(context.Background(), "Call", &emptypb.Empty{}, &emptypb.Empty{})
+			if status.Code(err) != c.wantCode {
+				t.Fatalf("expected %s, got %v", c.wantCode, err)
+			}
+			if got := atomic.LoadInt32(&calls); got != c.wantCalls {
+				t.Fatalf("expected %d calls, got %d", c.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestCallPerAttemptTimeout(t *testing.T) {
+	// 第一次调用超过方法超时，超时只作用于单次调用，重试后成功
+	var calls int32
+	pool := newTestPool(t, &Cfg{
+		DialFailMode: FailModeFailover,
+		RetryTimes:   1,
+		RetryCodes:   []string{"DeadlineExceeded"},
+		RetryBackoff: time.Millisecond,
+		Methods:      map[string]*MethodCfg{"Call": {Timeout: 50 * time.Millisecond}},
+	}, &testService{unary: func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}})
+	if err := pool.Call(context.Background(), "Call", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestCallGiveUpBeforeDeadline(t *testing.T) {
+	var calls int32
+	pool := newTestPool(t, &Cfg{DialFailMode: FailModeFailover, RetryTimes: 3, RetryBackoff: time.Second},
+		failingService(5, status.Error(codes.Unavailable, "down"), &calls))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := pool.Call(ctx, "Call", &emptypb.Empty{}, &emptypb.Empty{})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the last Unavailable error, got %v", err)
+	}
+	if calls != 1 || time.Since(start) > 150*time.Millisecond {
+		t.Fatalf("expected to give up without waiting, calls %d after %s", calls, time.Since(start))
+	}
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"google.golang.org/grpc/metadata"
+)
+
+// RouteMetadataPrefix 请求通过 outgoing metadata 指定节点子集，如 x-route-version: v2 仅调用 version=v2 的节点
+const RouteMetadataPrefix = "x-route-"
+
+// RouteCfg 路由配置，对应 [rpc.client.<name>.route]，按 require -> 请求指定 -> 金丝雀 -> 同区优先 的顺序过滤节点
+type RouteCfg struct {
+	Require map[string]string `toml:"require"` // 节点元数据必须全部匹配，如 version = "v2"，不匹配的节点不建立连接
+	Zone    string            `toml:"zone"`    // 本地可用区，存在同区节点时仅选择同区节点
+	Canary  *CanaryCfg        `toml:"canary"`
+}
+
+// CanaryCfg 金丝雀配置：命中的请求只发往金丝雀节点，其余请求排除金丝雀节点
+type CanaryCfg struct {
+	Match   map[string]string `toml:"match"`   // 金丝雀节点的元数据，如 version = "v3"
+	Header  string            `toml:"header"`  // metadata 中该 key 非空且不为 false 时命中
+	Percent float64           `toml:"percent"` // 按比例随机命中，取值 0-100
+}
+
+type router struct {
+	cfg *RouteCfg
+}
+
+func newRouter(cfg *RouteCfg) *router {
+	if cfg == nil {
+		cfg = &RouteCfg{}
+	}
+	return &router{cfg: cfg}
+}
+
+// filter 在连接池更新节点时提前剔除不满足 require 的节点
+func (r *router) filter(kv *discovery.KVPair) bool {
+	return matchMetadata(instance.FromValue(kv.Value), r.cfg.Require)
+}
+
+// route 返回本次请求可选的节点，结果为空表示没有满足路由规则的节点
+func (r *router) route(ctx context.Context, nodes []*instance.ServiceInstance) []*instance.ServiceInstance {
+	nodes = subset(nodes, r.cfg.Require)
+	if pins := routePins(ctx); len(pins) > 0 {
+		nodes = subset(nodes, pins)
+	}
+
+	if canary := r.cfg.Canary; canary != nil && len(canary.Match) > 0 {
+		if r.hitCanary(ctx) {
+			if list := subset(nodes, canary.Match); len(list) > 0 {
+				nodes = list
+			}
+		} else {
+			// 非金丝雀请求排除金丝雀节点，只剩金丝雀节点时不排除
+			list := make([]*instance.ServiceInstance, 0, len(nodes))
+			for _, node := range nodes {
+				if !matchMetadata(node, canary.Match) {
+					list = append(list, node)
+				}
+			}
+			if len(list) > 0 {
+				nodes = list
+			}
+		}
+	}
+
+	if r.cfg.Zone != "" {
+		if list := subset(nodes, map[string]string{instance.MetadataZone: r.cfg.Zone}); len(list) > 0 {
+			nodes = list
+		}
+	}
+	return nodes
+}
+
+func (r *router) hitCanary(ctx context.Context) bool {
+	canary := r.cfg.Canary
+	if canary.Header != "" {
+		v := metadataValue(ctx, strings.ToLower(canary.Header))
+		if v != "" && v != "false" {
+			return true
+		}
+	}
+	return canary.Percent > 0 && rand.Float64()*100 < canary.Percent
+}
+
+// routePins 解析 outgoing metadata 中 x-route-<key> 指定的元数据
+func routePins(ctx context.Context) map[string]string {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return nil
+	}
+	var pins map[string]string
+	for k, v := range md {
+		if !strings.HasPrefix(k, RouteMetadataPrefix) || len(v) == 0 || v[0] == "" {
+			continue
+		}
+		if pins == nil {
+			pins = map[string]string{}
+		}
+		pins[strings.TrimPrefix(k, RouteMetadataPrefix)] = v[0]
+	}
+	return pins
+}
+
+// metadataValue 依次从 outgoing、incoming metadata 中读取 key
+func metadataValue(ctx context.Context, key string) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func matchMetadata(node *instance.ServiceInstance, want map[string]string) bool {
+	for k, v := range want {
+		if node.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func subset(nodes []*instance.ServiceInstance, want map[string]string) []*instance.ServiceInstance {
+	if len(want) == 0 {
+		return nodes
+	}
+	list := make([]*instance.ServiceInstance, 0, len(nodes))
+	for _, node := range nodes {
+		if matchMetadata(node, want) {
+			list = append(list, node)
+		}
+	}
+	return list
+}
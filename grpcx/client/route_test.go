@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRoute(t *testing.T) {
+	node := func(addr, version, zone string) *instance.ServiceInstance {
+		return &instance.ServiceInstance{Address: addr, Metadata: map[string]string{
+			instance.MetadataVersion: version, instance.MetadataZone: zone}}
+	}
+	nodes := []*instance.ServiceInstance{
+		node("a", "v1", "sh"), node("b", "v1", "bj"), node("c", "v2", "sh"),
+	}
+	addrs := func(list []*instance.ServiceInstance) (r []string) {
+		for _, n := range list {
+			r = append(r, n.Address)
+		}
+		return
+	}
+	r := newRouter(&RouteCfg{
+		Zone:   "sh",
+		Canary: &CanaryCfg{Match: map[string]string{instance.MetadataVersion: "v2"}, Header: "x-canary"},
+	})
+
+	ctx := context.Background()
+	if got := addrs(r.route(ctx, nodes)); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected same zone non canary node, got %v", got)
+	}
+	canaryCtx := metadata.AppendToOutgoingContext(ctx, "x-canary", "true")
+	if got := addrs(r.route(canaryCtx, nodes)); len(got) != 1 || got[0] != "c" {
+		t.Fatalf("expected canary node, got %v", got)
+	}
+	pinCtx := metadata.AppendToOutgoingContext(ctx, RouteMetadataPrefix+instance.MetadataZone, "bj")
+	if got := addrs(r.route(pinCtx, nodes)); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("expected pinned node, got %v", got)
+	}
+	pinCtx = metadata.AppendToOutgoingContext(ctx, RouteMetadataPrefix+instance.MetadataVersion, "v3")
+	if got := r.route(pinCtx, nodes); len(got) != 0 {
+		t.Fatalf("expected no node, got %v", addrs(got))
+	}
+}
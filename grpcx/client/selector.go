@@ -1,21 +1,30 @@
 package client
 
 import (
+	"context"
 	"math/rand"
 	"strings"
 	"sync/atomic"
+	"time"
 
-	"github.com/liweiming-nova/common/grpcx/discovery"
 	"github.com/liweiming-nova/common/grpcx/instance"
 )
 
 // Selector 节点选择接口
 type Selector interface {
-	// Pick 在 kvPairs 中选择一个目标地址（host:port），返回空字符串表示不可用
-	Pick(kvPairs []*discovery.KVPair) string
+	// Pick 在已解码且经过路由过滤的节点中选择一个，返回 nil 表示不可用
+	Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance
+	// Done 反馈 Pick 选中节点的调用结果，每次 Pick 返回非 nil 后恰好调用一次
+	Done(node *instance.ServiceInstance, info DoneInfo)
 }
 
-// SelectorFactory 选择器工厂
+// DoneInfo 调用结果
+type DoneInfo struct {
+	Err      error
+	Duration time.Duration // 调用耗时，为 0 表示未测量（如流式调用或仅获取连接）
+}
+
+// SelectorFactory 选择器工厂，p 可能为 nil（例如在 gRPC balancer 中使用时）
 type SelectorFactory func(p *GrpcClientPool) Selector
 
 var selectorRegistry = map[string]SelectorFactory{}
@@ -35,18 +44,20 @@ func GetSelector(name string, p *GrpcClientPool) Selector {
 
 // ---- 内置实现 ----
 
-type roundRobinSelector struct{ p *GrpcClientPool }
+type roundRobinSelector struct{ idx uint64 }
 type roundRobinFactory struct{}
 
-func (f *roundRobinFactory) New(p *GrpcClientPool) Selector { return &roundRobinSelector{p: p} }
+func (f *roundRobinFactory) New(p *GrpcClientPool) Selector { return &roundRobinSelector{} }
+
+func (s *roundRobinSelector) Done(*instance.ServiceInstance, DoneInfo) {}
 
-func (s *roundRobinSelector) Pick(kvPairs []*discovery.KVPair) string {
-	n := len(kvPairs)
+func (s *roundRobinSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	n := len(nodes)
 	if n == 0 {
-		return ""
+		return nil
 	}
-	idx := int(atomic.AddUint64(&s.p.addrIdx, 1) % uint64(n))
-	return instance.ExtractAddress(kvPairs[idx].Value)
+	idx := int(atomic.AddUint64(&s.idx, 1) % uint64(n))
+	return nodes[idx]
 }
 
 type randomSelector struct{}
@@ -54,13 +65,14 @@ type randomFactory struct{}
 
 func (f *randomFactory) New(p *GrpcClientPool) Selector { return &randomSelector{} }
 
-func (s *randomSelector) Pick(kvPairs []*discovery.KVPair) string {
-	n := len(kvPairs)
+func (s *randomSelector) Done(*instance.ServiceInstance, DoneInfo) {}
+
+func (s *randomSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	n := len(nodes)
 	if n == 0 {
-		return ""
+		return nil
 	}
-	idx := rand.Intn(n)
-	return instance.ExtractAddress(kvPairs[idx].Value)
+	return nodes[rand.Intn(n)]
 }
 
 type scoreSelector struct{}
@@ -68,10 +80,12 @@ type scoreFactory struct{}
 
 func (f *scoreFactory) New(p *GrpcClientPool) Selector { return &scoreSelector{} }
 
-func (s *scoreSelector) Pick(kvPairs []*discovery.KVPair) string {
-	n := len(kvPairs)
+func (s *scoreSelector) Done(*instance.ServiceInstance, DoneInfo) {}
+
+func (s *scoreSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	n := len(nodes)
 	if n == 0 {
-		return ""
+		return nil
 	}
 	// 抽样 3 选最优
 	bestIdx, bestScore := 0, float64(0)
@@ -84,17 +98,20 @@ func (s *scoreSelector) Pick(kvPairs []*discovery.KVPair) string {
 		if n > sample {
 			idx = rand.Intn(n)
 		}
-		score := instance.ExtractScore(kvPairs[idx].Value)
+		score := nodes[idx].Weight()
 		if score > bestScore {
 			bestScore = score
 			bestIdx = idx
 		}
 	}
-	return instance.ExtractAddress(kvPairs[bestIdx].Value)
+	return nodes[bestIdx]
 }
 
 func init() {
 	RegisterSelector(SelectModeRoundRobin, func(p *GrpcClientPool) Selector { return (&roundRobinFactory{}).New(p) })
 	RegisterSelector(SelectModeRandom, func(p *GrpcClientPool) Selector { return (&randomFactory{}).New(p) })
 	RegisterSelector(SelectModeScore, func(p *GrpcClientPool) Selector { return (&scoreFactory{}).New(p) })
+	RegisterSelector(SelectModeWeightedRoundRobin, func(p *GrpcClientPool) Selector { return newWeightedRoundRobinSelector() })
+	RegisterSelector(SelectModeP2C, func(p *GrpcClientPool) Selector { return newP2CSelector() })
+	RegisterSelector(SelectModeConsistentHash, func(p *GrpcClientPool) Selector { return newConsistentHashSelector() })
 }
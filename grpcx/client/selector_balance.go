@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"google.golang.org/grpc/metadata"
+)
+
+// ---- 平滑加权轮询 ----
+
+// weightedRoundRobinSelector 平滑加权轮询（nginx smooth weighted round robin），权重取 ServiceInstance.Weight()
+type weightedRoundRobinSelector struct {
+	mu      sync.Mutex
+	current map[string]float64 // address -> current weight
+}
+
+func newWeightedRoundRobinSelector() Selector {
+	return &weightedRoundRobinSelector{current: map[string]float64{}}
+}
+
+func (s *weightedRoundRobinSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	if len(nodes) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best *instance.ServiceInstance
+	total := float64(0)
+	for _, node := range nodes {
+		w := node.Weight()
+		total += w
+		s.current[node.Address] += w
+		if best == nil || s.current[node.Address] > s.current[best.Address] {
+			best = node
+		}
+	}
+	s.current[best.Address] -= total
+
+	// 清理已下线节点
+	if len(s.current) > 2*len(nodes) {
+		alive := make(map[string]float64, len(nodes))
+		for _, node := range nodes {
+			alive[node.Address] = s.current[node.Address]
+		}
+		s.current = alive
+	}
+	return best
+}
+
+func (s *weightedRoundRobinSelector) Done(*instance.ServiceInstance, DoneInfo) {}
+
+// ---- P2C + EWMA ----
+
+const (
+	ewmaDecay    = 10 * time.Second // EWMA 衰减时间常数
+	errorPenalty = time.Second      // 节点故障类错误按该耗时计入 EWMA
+)
+
+type p2cStats struct {
+	inflight int64
+	ewma     float64 // 纳秒
+	last     time.Time
+}
+
+// p2cSelector 随机取两个节点，选择 (EWMA 延迟 + 1) * (在途数 + 1) / 权重 更小者
+type p2cSelector struct {
+	mu    sync.Mutex
+	stats map[string]*p2cStats
+}
+
+func newP2CSelector() Selector {
+	return &p2cSelector{stats: map[string]*p2cStats{}}
+}
+
+func (s *p2cSelector) stat(addr string) *p2cStats {
+	st, ok := s.stats[addr]
+	if !ok {
+		st = &p2cStats{}
+		s.stats[addr] = st
+	}
+	return st
+}
+
+func (s *p2cSelector) load(node *instance.ServiceInstance) float64 {
+	st := s.stat(node.Address)
+	return (st.ewma + 1) * float64(st.inflight+1) / node.Weight()
+}
+
+func (s *p2cSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	n := len(nodes)
+	if n == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := nodes[0]
+	if n > 1 {
+		i := rand.Intn(n)
+		j := rand.Intn(n - 1)
+		if j >= i {
+			j++
+		}
+		best = nodes[i]
+		if s.load(nodes[j]) < s.load(best) {
+			best = nodes[j]
+		}
+	}
+	s.stat(best.Address).inflight++
+
+	if len(s.stats) > 2*n {
+		alive := make(map[string]*p2cStats, n)
+		for _, node := range nodes {
+			alive[node.Address] = s.stat(node.Address)
+		}
+		s.stats = alive
+	}
+	return best
+}
+
+func (s *p2cSelector) Done(node *instance.ServiceInstance, info DoneInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[node.Address]
+	if !ok {
+		return
+	}
+	if st.inflight > 0 {
+		st.inflight--
+	}
+	if info.Duration <= 0 {
+		return
+	}
+
+	d := info.Duration
+	if info.Err != nil && isBreakerFailure(info.Err) && d < errorPenalty {
+		d = errorPenalty
+	}
+	now := time.Now()
+	if st.last.IsZero() {
+		st.ewma = float64(d)
+	} else {
+		w := math.Exp(-float64(now.Sub(st.last)) / float64(ewmaDecay))
+		st.ewma = st.ewma*w + float64(d)*(1-w)
+	}
+	st.last = now
+}
+
+// ---- 一致性哈希 ----
+
+// HashKeyMetadata 未通过 WithHashKey 指定时，从 outgoing metadata 的该 key 读取哈希 key
+const HashKeyMetadata = "x-hash-key"
+
+const hashReplicas = 100 // 每单位权重的虚拟节点数
+
+type hashKey struct{}
+
+// WithHashKey 为 consistent_hash 选择器指定本次请求的哈希 key，相同 key 落到同一节点
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+func requestHashKey(ctx context.Context) string {
+	if key, ok := ctx.Value(hashKey{}).(string); ok && key != "" {
+		return key
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(HashKeyMetadata); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+type hashRing struct {
+	signature string
+	hashes    []uint32
+	nodes     map[uint32]*instance.ServiceInstance
+}
+
+// consistentHashSelector 按请求 key 在虚拟节点环上选择节点，未携带 key 时随机
+type consistentHashSelector struct {
+	mu   sync.Mutex
+	ring *hashRing
+}
+
+func newConsistentHashSelector() Selector {
+	return &consistentHashSelector{}
+}
+
+func (s *consistentHashSelector) Pick(ctx context.Context, nodes []*instance.ServiceInstance) *instance.ServiceInstance {
+	if len(nodes) == 0 {
+		return nil
+	}
+	key := requestHashKey(ctx)
+	if key == "" {
+		return nodes[rand.Intn(len(nodes))]
+	}
+
+	ring := s.getRing(nodes)
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= h })
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.nodes[ring.hashes[i]]
+}
+
+func (s *consistentHashSelector) Done(*instance.ServiceInstance, DoneInfo) {}
+
+// getRing 节点集合变化时重建哈希环
+func (s *consistentHashSelector) getRing(nodes []*instance.ServiceInstance) *hashRing {
+	addrs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		addrs = append(addrs, node.Address+"#"+strconv.FormatFloat(node.Weight(), 'f', -1, 64))
+	}
+	sort.Strings(addrs)
+	signature := strings.Join(addrs, ",")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ring != nil && s.ring.signature == signature {
+		return s.ring
+	}
+
+	ring := &hashRing{signature: signature, nodes: map[uint32]*instance.ServiceInstance{}}
+	for _, node := range nodes {
+		replicas := int(math.Ceil(node.Weight() * hashReplicas))
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(node.Address + "#" + strconv.Itoa(i)))
+			if _, ok := ring.nodes[h]; ok {
+				continue
+			}
+			ring.nodes[h] = node
+			ring.hashes = append(ring.hashes, h)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	s.ring = ring
+	return ring
+}
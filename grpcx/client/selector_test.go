@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+)
+
+func TestWeightedRoundRobin(t *testing.T) {
+	nodes := []*instance.ServiceInstance{{Address: "a", Score: 5}, {Address: "b", Score: 1}, {Address: "c", Score: 1}}
+	s := newWeightedRoundRobinSelector()
+	counts := map[string]int{}
+	for i := 0; i < 70; i++ {
+		counts[s.Pick(context.Background(), nodes).Address]++
+	}
+	if counts["a"] != 50 || counts["b"] != 10 || counts["c"] != 10 {
+		t.Fatalf("unexpected distribution %v", counts)
+	}
+}
+
+func TestP2C(t *testing.T) {
+	nodes := []*instance.ServiceInstance{{Address: "slow"}, {Address: "fast"}}
+	s := newP2CSelector()
+	for _, node := range nodes {
+		s.Pick(context.Background(), []*instance.ServiceInstance{node})
+	}
+	s.Done(nodes[0], DoneInfo{Duration: 500 * time.Millisecond})
+	s.Done(nodes[1], DoneInfo{Duration: time.Millisecond})
+
+	for i := 0; i < 10; i++ {
+		node := s.Pick(context.Background(), nodes)
+		if node.Address != "fast" {
+			t.Fatalf("expected fast node, got %s", node.Address)
+		}
+		s.Done(node, DoneInfo{Duration: time.Millisecond})
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	nodes := []*instance.ServiceInstance{{Address: "a"}, {Address: "b"}, {Address: "c"}}
+	s := newConsistentHashSelector()
+	ctx := WithHashKey(context.Background(), "user:42")
+	first := s.Pick(ctx, nodes).Address
+	for i := 0; i < 10; i++ {
+		if got := s.Pick(ctx, nodes).Address; got != first {
+			t.Fatalf("expected %s, got %s", first, got)
+		}
+	}
+
+	// 移除其他节点不影响该 key 的落点
+	var rest []*instance.ServiceInstance
+	for _, node := range nodes {
+		if node.Address == first || len(rest) == 0 {
+			rest = append(rest, node)
+		}
+	}
+	if got := s.Pick(ctx, rest).Address; got != first {
+		t.Fatalf("expected %s after removing nodes, got %s", first, got)
+	}
+}
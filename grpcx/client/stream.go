@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// NewStream 通过连接池创建流式调用（server/client/bidi streaming）
+// 与 Call 共用服务发现、节点选择、熔断与 trace_id 透传；调用方应读到 io.EOF 或取消 ctx 以及时释放流
+func (p *GrpcClientPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	normalized, err := p.fullMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	// 注入 trace_id 到 outgoing metadata
+	ctx = withTraceID(ctx)
+
+	if !p.breaker.ready() {
+		return nil, &CircuitOpenError{Service: p.serviceName}
+	}
+	ep, node, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !p.breaker.allow() {
+		ep.release()
+		err = &CircuitOpenError{Service: p.serviceName}
+		p.feedback(node, DoneInfo{Err: err})
+		return nil, err
+	}
+	if !ep.breaker.allow() {
+		ep.release()
+		p.breaker.release()
+		err = &CircuitOpenError{Service: p.serviceName, Endpoint: ep.addr}
+		p.feedback(node, DoneInfo{Err: err})
+		return nil, err
+	}
+
+	// 节点的在途计数已在 pick 中占用，流结束时释放
+	atomic.AddInt64(&p.inflight, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	st := &streamState{}
+	// 上游取消时同样释放计数；AfterFunc 不占用 goroutine，流正常结束时注销
+	stop := context.AfterFunc(ctx, func() { st.done(ctx.Err()) })
+	st.finish = func(err error) {
+		stop()
+		cancel()
+		// 熔断按流的最终结果记录，而不只是建流结果
+		p.breaker.record(err)
+		ep.breaker.record(err)
+		p.feedback(node, DoneInfo{Err: err})
+		ep.release()
+		atomic.AddInt64(&p.inflight, -1)
+	}
+
+	cs, err := ep.conn().NewStream(ctx, desc, normalized, opts...)
+	if err != nil {
+		st.done(err)
+		return nil, err
+	}
+	s := &clientStream{ClientStream: cs, desc: desc, state: st}
+	// 调用方既未读到结束也未取消 ctx 就丢弃流时，由 finalizer 兜底释放；st 不引用 s，保证 s 可被回收
+	runtime.SetFinalizer(s, func(s *clientStream) { s.state.done(context.Canceled) })
+	return s, nil
+}
+
+// streamState 流的结束状态，与 clientStream 分离，避免 ctx 回调持有 clientStream 导致 finalizer 无法触发
+type streamState struct {
+	once   sync.Once
+	finish func(err error) // err 为结束原因，正常结束为 nil
+}
+
+// done 只有第一次调用生效，结束原因随 once 传给 finish，避免与取消时的并发调用竞争
+func (s *streamState) done(err error) {
+	s.once.Do(func() { s.finish(err) })
+}
+
+// clientStream 在流结束时释放在途计数并记录熔断
+type clientStream struct {
+	grpc.ClientStream
+	desc  *grpc.StreamDesc
+	state *streamState
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	// 出错（含 CloseSend 后读到 io.EOF）或非服务端流收到唯一响应时，流已结束
+	if err != nil || !s.desc.ServerStreams {
+		if err == io.EOF {
+			s.state.done(nil)
+		} else {
+			s.state.done(err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var streamDesc = &grpc.StreamDesc{StreamName: "Stream", ServerStreams: true}
+
+// inflight 返回连接池与 bufnet 节点的在途计数
+func inflight(p *GrpcClientPool) (int64, int64) {
+	return atomic.LoadInt64(&p.inflight), atomic.LoadInt64(&p.endpoint("passthrough:///bufnet").inflight)
+}
+
+func openStream(t *testing.T, ctx context.Context, pool *GrpcClientPool) grpc.ClientStream {
+	t.Helper()
+	cs, err := pool.NewStream(ctx, streamDesc, "Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SendMsg(&emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	return cs
+}
+
+func TestStreamReleaseOnEOF(t *testing.T) {
+	pool := newTestPool(t, &Cfg{}, &testService{stream: func(stream grpc.ServerStream) error {
+		for i := 0; i < 2; i++ {
+			if err := stream.SendMsg(&emptypb.Empty{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+
+	cs := openStream(t, context.Background(), pool)
+	if p, e := inflight(pool); p != 1 || e != 1 {
+		t.Fatalf("inflight during stream = %d/%d, want 1/1", p, e)
+	}
+	n := 0
+	for {
+		err := cs.RecvMsg(&emptypb.Empty{})
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("received %d messages, want 2", n)
+	}
+	if p, e := inflight(pool); p != 0 || e != 0 {
+		t.Fatalf("inflight after EOF = %d/%d, want 0/0", p, e)
+	}
+}
+
+func TestStreamReleaseOnCancel(t *testing.T) {
+	pool := newTestPool(t, &Cfg{}, &testService{stream: func(stream grpc.ServerStream) error {
+		<-stream.Context().Done()
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	openStream(t, ctx, pool)
+	cancel()
+	waitFor(t, "inflight released on cancel", func() bool {
+		p, e := inflight(pool)
+		return p == 0 && e == 0
+	})
+}
+
+func TestStreamReleaseOnAbandon(t *testing.T) {
+	pool := newTestPool(t, &Cfg{}, &testService{stream: func(stream grpc.ServerStream) error {
+		<-stream.Context().Done()
+		return nil
+	}})
+
+	// 既不读到结束也不取消 ctx，丢弃后由 finalizer 释放
+	openStream(t, context.Background(), pool)
+	waitFor(t, "inflight released on abandon", func() bool {
+		runtime.GC()
+		p, e := inflight(pool)
+		return p == 0 && e == 0
+	})
+}
+
+func TestStreamBreakerRecordsFinalError(t *testing.T) {
+	pool := newTestPool(t, &Cfg{
+		Breaker: &BreakerCfg{Enable: true, ConsecutiveFailures: 2, OpenDuration: time.Minute},
+	}, &testService{stream: func(stream grpc.ServerStream) error {
+		// 建流成功，失败在流结束时才返回
+		return status.Error(codes.Unavailable, "backend down")
+	}})
+
+	for i := 0; i < 2; i++ {
+		cs := openStream(t, context.Background(), pool)
+		if err := cs.RecvMsg(&emptypb.Empty{}); status.Code(err) != codes.Unavailable {
+			t.Fatalf("stream %d: got %v, want Unavailable", i, err)
+		}
+	}
+	if _, err := pool.NewStream(context.Background(), streamDesc, "Stream"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want circuit open after stream failures", err)
+	}
+}
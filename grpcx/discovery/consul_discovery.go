@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// ConsulDiscovery 通过 Consul 阻塞查询获取通过健康检查的实例，服务名取 servicePath 去掉 /services/ 前缀
+type ConsulDiscovery struct {
+	*MemoryDiscovery
+
+	cfg     *registry.ConsulCfg
+	client  *registry.ConsulClient
+	service string
+
+	stopOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	fetchErr
+}
+
+// NewConsulDiscovery 创建 Consul 服务发现，首次查询失败时记录日志并在后台重试
+func NewConsulDiscovery(servicePath string, cfg *registry.ConsulCfg) *ConsulDiscovery {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &ConsulDiscovery{
+		MemoryDiscovery: NewMemoryDiscovery(servicePath),
+		cfg:             cfg,
+		client:          registry.NewConsulClient(cfg),
+		service:         serviceName(servicePath),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+	index, err := d.query(0)
+	if err != nil {
+		xlog.Errorf(context.Background(), "ConsulDiscovery query %s error: %v", d.service, err)
+	}
+	go d.watch(index)
+	return d
+}
+
+// watch 使用 X-Consul-Index 阻塞查询，实例变化时推送
+func (d *ConsulDiscovery) watch(index uint64) {
+	backoff := minWatchBackoff
+	for {
+		next, err := d.query(index)
+		if d.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			xlog.Errorf(context.Background(), "ConsulDiscovery query %s error: %v", d.service, err)
+			select {
+			case <-time.After(backoff):
+			case <-d.ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > maxWatchBackoff {
+				backoff = maxWatchBackoff
+			}
+			continue
+		}
+		backoff = minWatchBackoff
+		// index 回退（如 Consul 重建）时重新开始，且必须大于 0 才会阻塞
+		if next < index || next == 0 {
+			next = 1
+		}
+		index = next
+	}
+}
+
+func (d *ConsulDiscovery) query(index uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.client.Cfg().WaitTime+10*time.Second)
+	defer cancel()
+	entries, next, err := d.client.HealthService(ctx, d.service, index)
+	if err != nil {
+		if d.ctx.Err() == nil {
+			d.fetchErr.set(err)
+		}
+		return 0, err
+	}
+	pairs := make([]*KVPair, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		address := net.JoinHostPort(host, strconv.Itoa(entry.Service.Port))
+		value, err := instance.Encode(instance.New(address, entry.Service.Meta, 0))
+		if err != nil {
+			return 0, err
+		}
+		pairs = append(pairs, &KVPair{Key: d.ServicePath() + "/" + address, Value: string(value)})
+	}
+	d.fetchErr.set(nil)
+	d.update(pairs)
+	return next, nil
+}
+
+// Clone 使用同一配置创建指定路径的服务发现
+func (d *ConsulDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	return NewConsulDiscovery(servicePath, d.cfg), nil
+}
+
+// Close 停止查询并关闭所有订阅
+func (d *ConsulDiscovery) Close() {
+	d.stopOnce.Do(d.cancel)
+	d.MemoryDiscovery.Close()
+}
+
+// serviceName 由服务路径得到注册中心中的服务名
+func serviceName(servicePath string) string {
+	return strings.TrimPrefix(servicePath, "/services/")
+}
@@ -1,9 +1,13 @@
 package discovery
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"sync"
+
+	"github.com/liweiming-nova/common/xlog"
 )
 
 // KVPair 服务实例键值对（rpcx 风格）
@@ -26,33 +30,244 @@ type ServiceDiscovery interface {
 	Close()
 }
 
-// CachedServiceDiscovery 带缓存的服务发现（你已实现，稍作优化）
+// ErrorReporter 可选接口，返回最近一次从注册中心获取实例列表的错误，成功时为 nil；
+// CachedServiceDiscovery 据此区分注册中心不可用与实例确实为空
+type ErrorReporter interface {
+	Err() error
+}
+
+// fetchErr 记录最近一次获取实例列表的错误，供各服务发现实现 ErrorReporter；需在推送新列表之前更新
+type fetchErr struct {
+	mu  sync.RWMutex
+	err error
+}
+
+func (e *fetchErr) set(err error) {
+	e.mu.Lock()
+	e.err = err
+	e.mu.Unlock()
+}
+
+// Err 实现 ErrorReporter
+func (e *fetchErr) Err() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.err
+}
+
+// ServicePather 可选接口，返回服务发现对应的服务路径，用作缓存 key
+type ServicePather interface {
+	ServicePath() string
+}
+
+// CachedServiceDiscovery 带缓存的服务发现：持久化最近一次健康的实例列表，
+// 底层服务发现出错（实现 ErrorReporter 且 Err 非 nil）时使用缓存，便于注册中心不可用时启动与调用；
+// 成功返回的空列表原样透传，threshold > 0 时实例数少于 threshold 也使用缓存
 type CachedServiceDiscovery struct {
 	threshold  int
 	cachedFile string
+	path       string // 缓存文件中的 key
+
+	cachedLock sync.RWMutex
 	cached     []*KVPair
 
 	chansLock sync.RWMutex
-	chans     map[chan []*KVPair]chan []*KVPair
+	chans     map[chan []*KVPair]*cachedWatcher // 对外 channel -> 底层订阅
 
 	ServiceDiscovery
 }
 
-// CacheDiscovery 包装器，支持缓存降级
+type cachedWatcher struct {
+	in   chan []*KVPair
+	stop chan struct{}
+}
+
+// CacheDiscovery 包装器，支持缓存降级，threshold <= 0 时仅在底层服务发现出错时使用缓存
 func CacheDiscovery(threshold int, cachedFile string, discovery ServiceDiscovery) ServiceDiscovery {
 	if cachedFile == "" {
 		cachedFile = ".cache/discovery.json"
 	}
+	if threshold < 0 {
+		threshold = 0
+	}
 
 	cachedFileDir := filepath.Dir(cachedFile)
 	if _, err := os.Stat(cachedFileDir); os.IsNotExist(err) {
 		_ = os.MkdirAll(cachedFileDir, 0755)
 	}
 
-	return &CachedServiceDiscovery{
+	path := "default"
+	if p, ok := discovery.(ServicePather); ok {
+		path = p.ServicePath()
+	}
+
+	d := &CachedServiceDiscovery{
 		threshold:        threshold,
 		cachedFile:       cachedFile,
+		path:             path,
 		ServiceDiscovery: discovery,
-		chans:            make(map[chan []*KVPair]chan []*KVPair),
+		chans:            make(map[chan []*KVPair]*cachedWatcher),
+	}
+	if snapshot, err := readSnapshot(cachedFile); err == nil {
+		d.cached = snapshot[path]
+	}
+	return d
+}
+
+// GetServices 返回底层实例，底层出错或不足 threshold 时退化为缓存
+func (d *CachedServiceDiscovery) GetServices() []*KVPair {
+	return d.resolve(d.ServiceDiscovery.GetServices())
+}
+
+// resolve 获取成功且满足 threshold 时更新缓存并原样返回，否则使用缓存
+func (d *CachedServiceDiscovery) resolve(pairs []*KVPair) []*KVPair {
+	var err error
+	if r, ok := d.ServiceDiscovery.(ErrorReporter); ok {
+		err = r.Err()
+	}
+	if err == nil && len(pairs) >= d.threshold {
+		// 空列表不覆盖缓存，注册中心之后不可用时仍可回退到最近一次非空的实例
+		if len(pairs) > 0 {
+			d.save(pairs)
+		}
+		return pairs
+	}
+
+	d.cachedLock.RLock()
+	cached := d.cached
+	d.cachedLock.RUnlock()
+	if len(cached) > len(pairs) {
+		xlog.Warnf(context.Background(), "CachedServiceDiscovery %s got %d instances (err: %v), fallback to %d cached", d.path, len(pairs), err, len(cached))
+		return cached
+	}
+	return pairs
+}
+
+// save 实例列表变化时写入缓存文件
+func (d *CachedServiceDiscovery) save(pairs []*KVPair) {
+	d.cachedLock.Lock()
+	defer d.cachedLock.Unlock()
+	if equalPairs(d.cached, pairs) {
+		return
+	}
+	d.cached = pairs
+	if err := writeSnapshot(d.cachedFile, d.path, pairs); err != nil {
+		xlog.Errorf(context.Background(), "CachedServiceDiscovery write %s error: %v", d.cachedFile, err)
+	}
+}
+
+// WatchService 订阅底层变更，推送前按缓存策略处理
+func (d *CachedServiceDiscovery) WatchService() chan []*KVPair {
+	w := &cachedWatcher{in: d.ServiceDiscovery.WatchService(), stop: make(chan struct{})}
+	out := make(chan []*KVPair, 10)
+
+	d.chansLock.Lock()
+	d.chans[out] = w
+	d.chansLock.Unlock()
+
+	go func() {
+		defer close(out)
+		for pairs := range w.in {
+			select {
+			case out <- d.resolve(pairs):
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// RemoveWatcher 取消订阅并关闭对外 channel
+func (d *CachedServiceDiscovery) RemoveWatcher(ch chan []*KVPair) {
+	d.chansLock.Lock()
+	w, ok := d.chans[ch]
+	delete(d.chans, ch)
+	d.chansLock.Unlock()
+	if ok {
+		close(w.stop)
+		d.ServiceDiscovery.RemoveWatcher(w.in)
+	}
+}
+
+// Clone 克隆底层服务发现并使用同一缓存文件
+func (d *CachedServiceDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	inner, err := d.ServiceDiscovery.Clone(servicePath)
+	if err != nil {
+		return nil, err
+	}
+	return CacheDiscovery(d.threshold, d.cachedFile, inner), nil
+}
+
+// Close 取消所有订阅并关闭底层服务发现
+func (d *CachedServiceDiscovery) Close() {
+	d.chansLock.Lock()
+	chans := d.chans
+	d.chans = make(map[chan []*KVPair]*cachedWatcher)
+	d.chansLock.Unlock()
+	for _, w := range chans {
+		close(w.stop)
+		d.ServiceDiscovery.RemoveWatcher(w.in)
+	}
+	d.ServiceDiscovery.Close()
+}
+
+// ServicePath 返回缓存使用的服务路径
+func (d *CachedServiceDiscovery) ServicePath() string {
+	return d.path
+}
+
+func equalPairs(a, b []*KVPair) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 同一进程内多个服务共享缓存文件，读写需串行
+var snapshotLock sync.Mutex
+
+// readSnapshot 读取缓存文件：servicePath -> 实例列表
+func readSnapshot(file string) (map[string][]*KVPair, error) {
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+	return readSnapshotLocked(file)
+}
+
+func readSnapshotLocked(file string) (map[string][]*KVPair, error) {
+	snapshot := map[string][]*KVPair{}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+// writeSnapshot 更新缓存文件中指定服务的实例列表，先写临时文件再重命名
+func writeSnapshot(file, path string, pairs []*KVPair) error {
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+
+	snapshot, err := readSnapshotLocked(file)
+	if err != nil && !os.IsNotExist(err) {
+		xlog.Warnf(context.Background(), "CachedServiceDiscovery read %s error, rewrite: %v", file, err)
+		snapshot = map[string][]*KVPair{}
+	}
+	snapshot[path] = pairs
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
 	}
+	return os.Rename(tmp, file)
 }
@@ -0,0 +1,146 @@
+package discovery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+)
+
+type stubDiscovery struct {
+	path  string
+	pairs []*KVPair
+	err   error
+}
+
+func (s *stubDiscovery) GetServices() []*KVPair                             { return s.pairs }
+func (s *stubDiscovery) WatchService() chan []*KVPair                       { return make(chan []*KVPair) }
+func (s *stubDiscovery) RemoveWatcher(chan []*KVPair)                       {}
+func (s *stubDiscovery) Clone(servicePath string) (ServiceDiscovery, error) { return s, nil }
+func (s *stubDiscovery) SetFilter(ServiceDiscoveryFilter)                   {}
+func (s *stubDiscovery) Close()                                             {}
+func (s *stubDiscovery) ServicePath() string                                { return s.path }
+func (s *stubDiscovery) Err() error                                         { return s.err }
+
+func TestCacheDiscovery(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "discovery.json")
+	pairs := []*KVPair{{Key: "/services/user/a", Value: "a"}, {Key: "/services/user/b", Value: "b"}}
+
+	user := &stubDiscovery{path: "/services/user", pairs: pairs}
+	order := &stubDiscovery{path: "/services/order", pairs: pairs[:1]}
+	if got := CacheDiscovery(2, file, user).GetServices(); len(got) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(got))
+	}
+	CacheDiscovery(1, file, order).GetServices()
+
+	// 注册中心不可用：重新创建的实例从缓存文件恢复
+	user.pairs = nil
+	if got := CacheDiscovery(2, file, user).GetServices(); len(got) != 2 || got[1].Value != "b" {
+		t.Fatalf("expected cached instances, got %v", got)
+	}
+	order.pairs = nil
+	if got := CacheDiscovery(1, file, order).GetServices(); len(got) != 1 || got[0].Value != "a" {
+		t.Fatalf("expected cached instances, got %v", got)
+	}
+}
+
+func TestCacheDiscoveryOnError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "discovery.json")
+	user := &stubDiscovery{path: "/services/user", pairs: []*KVPair{{Key: "/services/user/a", Value: "a"}}}
+	d := CacheDiscovery(0, file, user)
+	if got := d.GetServices(); len(got) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(got))
+	}
+
+	// 成功返回的空列表原样透传
+	user.pairs = []*KVPair{}
+	if got := d.GetServices(); len(got) != 0 {
+		t.Fatalf("empty result without error should pass through, got %v", got)
+	}
+
+	// 注册中心出错时使用最近一次非空的缓存
+	user.pairs, user.err = nil, errors.New("registry unavailable")
+	if got := d.GetServices(); len(got) != 1 || got[0].Value != "a" {
+		t.Fatalf("expected cached instances on error, got %v", got)
+	}
+}
+
+func TestMemoryDiscovery(t *testing.T) {
+	d := NewStaticDiscovery("/services/user", []string{"b:1", "a:1"})
+	ch := d.WatchService()
+	if got := <-ch; len(got) != 2 || got[0].Value != "a:1" {
+		t.Fatalf("unexpected initial instances %v", got)
+	}
+
+	d.Add("/services/user/c:1", "c:1")
+	if got := <-ch; len(got) != 3 {
+		t.Fatalf("expected 3 instances, got %d", len(got))
+	}
+	d.Remove("/services/user/a:1")
+	d.Remove("/services/user/b:1")
+	d.Remove("/services/user/c:1")
+	var got []*KVPair
+	for len(ch) > 0 {
+		got = <-ch
+	}
+	if got == nil || len(got) != 0 {
+		t.Fatalf("expected empty instances pushed, got %v", got)
+	}
+
+	d.RemoveWatcher(ch)
+	if _, ok := <-ch; ok {
+		t.Fatal("expected watcher closed")
+	}
+}
+
+func TestFileDiscovery(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "user.toml")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("[[instances]]\naddress = \"127.0.0.1:8080\"\nscore = 2\n[instances.metadata]\nzone = \"az1\"\n")
+
+	d, err := NewFileDiscovery("/services/user", file, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	pairs := d.GetServices()
+	if len(pairs) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(pairs))
+	}
+	si := instance.FromValue(pairs[0].Value)
+	if si.Address != "127.0.0.1:8080" || si.Weight() != 2 || si.Metadata["zone"] != "az1" {
+		t.Fatalf("unexpected instance %+v", si)
+	}
+
+	ch := d.WatchService()
+	<-ch
+	write("[[instances]]\naddress = \"127.0.0.1:8080\"\n\n[[instances]]\naddress = \"127.0.0.1:8081\"\n")
+	select {
+	case pairs = <-ch:
+		if len(pairs) != 2 {
+			t.Fatalf("expected 2 instances, got %d", len(pairs))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("file change not detected")
+	}
+
+	jsonFile := filepath.Join(t.TempDir(), "user.json")
+	if err = os.WriteFile(jsonFile, []byte(`[{"address":"10.0.0.1:80"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jd, err := NewFileDiscovery("/services/user", jsonFile, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jd.Close()
+	if pairs = jd.GetServices(); len(pairs) != 1 || pairs[0].Key != "/services/user/10.0.0.1:80" {
+		t.Fatalf("unexpected instances %v", pairs)
+	}
+}
@@ -0,0 +1,207 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// DNS 实例写入 Metadata 的 SRV 字段
+const (
+	MetadataSRVPriority = "srv_priority"
+	MetadataSRVWeight   = "srv_weight"
+)
+
+// Resolver DNS 解析接口，*net.Resolver 满足该接口，测试时可替换
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DNSDiscovery 定期解析 DNS 记录得到实例列表，适用于无 etcd、通过无头服务暴露的场景
+//
+// target 为 host:port 时解析 A/AAAA 记录，每个 IP 使用该端口；
+// 否则按 SRV 记录名解析（如 _grpc._tcp.user.default.svc.cluster.local），
+// 只使用有可用目标的最小优先级（数值越小越优先），权重取 SRV weight；
+// 记录不存在（NXDOMAIN）视为实例为空，其他解析错误保留上一次的实例列表
+type DNSDiscovery struct {
+	*MemoryDiscovery
+
+	target   string
+	resolver Resolver
+	interval time.Duration
+	timeout  time.Duration
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	fetchErr
+}
+
+// DNSDiscoveryOption DNSDiscovery 选项
+type DNSDiscoveryOption func(d *DNSDiscovery)
+
+// WithDNSResolver 指定解析器，默认 net.DefaultResolver
+func WithDNSResolver(r Resolver) DNSDiscoveryOption {
+	return func(d *DNSDiscovery) {
+		if r != nil {
+			d.resolver = r
+		}
+	}
+}
+
+// WithDNSRefreshInterval 指定解析间隔，默认 30s
+func WithDNSRefreshInterval(interval time.Duration) DNSDiscoveryOption {
+	return func(d *DNSDiscovery) {
+		if interval > 0 {
+			d.interval = interval
+		}
+	}
+}
+
+// NewDNSDiscovery 创建 DNS 服务发现，首次解析失败时返回错误
+func NewDNSDiscovery(servicePath, target string, opts ...DNSDiscoveryOption) (*DNSDiscovery, error) {
+	d := &DNSDiscovery{
+		MemoryDiscovery: NewMemoryDiscovery(servicePath),
+		target:          target,
+		resolver:        net.DefaultResolver,
+		interval:        30 * time.Second,
+		timeout:         5 * time.Second,
+		stop:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if err := d.refresh(); err != nil {
+		return nil, err
+	}
+	go d.watch()
+	return d, nil
+}
+
+func (d *DNSDiscovery) watch() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.refresh(); err != nil {
+				xlog.Errorf(context.Background(), "DNSDiscovery resolve %s error: %v", d.target, err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// refresh 解析一次，结果变化时推送；记录不存在时推送空列表，其他解析失败保留上一次的实例列表
+func (d *DNSDiscovery) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	instances, err := d.resolve(ctx)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		xlog.Warnf(context.Background(), "DNSDiscovery %s not found, no instances: %v", d.target, err)
+		instances, err = nil, nil
+	}
+	if err != nil {
+		d.fetchErr.set(err)
+		return err
+	}
+	pairs := make([]*KVPair, 0, len(instances))
+	for _, si := range instances {
+		value, err := instance.Encode(si)
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, &KVPair{Key: d.ServicePath() + "/" + si.Address, Value: string(value)})
+	}
+	d.fetchErr.set(nil)
+	d.update(pairs)
+	return nil
+}
+
+func (d *DNSDiscovery) resolve(ctx context.Context) ([]*instance.ServiceInstance, error) {
+	if host, port, err := net.SplitHostPort(d.target); err == nil {
+		addrs, err := d.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		instances := make([]*instance.ServiceInstance, 0, len(addrs))
+		for _, addr := range addrs {
+			instances = append(instances, &instance.ServiceInstance{Address: net.JoinHostPort(addr.IP.String(), port)})
+		}
+		return instances, nil
+	}
+
+	_, records, err := d.resolver.LookupSRV(ctx, "", "", d.target)
+	if err != nil {
+		return nil, err
+	}
+	return srvInstances(records), nil
+}
+
+// srvInstances 将有可用目标的最小优先级的 SRV 记录转换为实例，同一地址只保留第一条；
+// 目标为 "." 或端口为 0 的记录表示不可用（RFC 2782），不参与优先级选择
+func srvInstances(records []*net.SRV) []*instance.ServiceInstance {
+	var usable []*net.SRV
+	for _, srv := range records {
+		if srv.Port == 0 || strings.TrimSuffix(srv.Target, ".") == "" {
+			continue
+		}
+		usable = append(usable, srv)
+	}
+	if len(usable) == 0 {
+		return nil
+	}
+	top := usable[0].Priority
+	for _, srv := range usable {
+		if srv.Priority < top {
+			top = srv.Priority
+		}
+	}
+
+	seen := map[string]bool{}
+	instances := make([]*instance.ServiceInstance, 0, len(usable))
+	for _, srv := range usable {
+		if srv.Priority != top {
+			continue
+		}
+		addr := net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		weight := float64(srv.Weight)
+		if weight <= 0 {
+			weight = 1
+		}
+		instances = append(instances, &instance.ServiceInstance{
+			Address: addr,
+			Score:   weight,
+			Metadata: map[string]string{
+				MetadataSRVPriority: strconv.Itoa(int(srv.Priority)),
+				MetadataSRVWeight:   strconv.Itoa(int(srv.Weight)),
+			},
+		})
+	}
+	return instances
+}
+
+// Clone 使用同一 target 创建指定路径的服务发现
+func (d *DNSDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	return NewDNSDiscovery(servicePath, d.target, WithDNSResolver(d.resolver), WithDNSRefreshInterval(d.interval))
+}
+
+// Close 停止解析并关闭所有订阅
+func (d *DNSDiscovery) Close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.MemoryDiscovery.Close()
+}
@@ -0,0 +1,140 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+)
+
+type stubResolver struct {
+	mu  sync.Mutex
+	srv []*net.SRV
+	ips []net.IPAddr
+	err error
+}
+
+func (r *stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return name, r.srv, r.err
+}
+
+func (r *stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ips, r.err
+}
+
+func (r *stubResolver) set(srv []*net.SRV, err error) {
+	r.mu.Lock()
+	r.srv, r.err = srv, err
+	r.mu.Unlock()
+}
+
+func TestDNSDiscoverySRV(t *testing.T) {
+	r := &stubResolver{srv: []*net.SRV{
+		{Target: "a.user.svc.", Port: 8080, Priority: 10, Weight: 5},
+		{Target: "b.user.svc.", Port: 8080, Priority: 10, Weight: 0},
+		{Target: "c.user.svc.", Port: 8080, Priority: 20, Weight: 5},
+	}}
+	d, err := NewDNSDiscovery("/services/user", "_grpc._tcp.user.svc", WithDNSResolver(r), WithDNSRefreshInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	scores := map[string]float64{}
+	for _, pair := range d.GetServices() {
+		si := instance.FromValue(pair.Value)
+		scores[si.Address] = si.Weight()
+	}
+	// 只使用最小优先级的目标
+	if len(scores) != 2 || scores["a.user.svc:8080"] != 5 || scores["b.user.svc:8080"] != 1 {
+		t.Fatalf("unexpected scores %v", scores)
+	}
+
+	ch := d.WatchService()
+	<-ch
+	r.set(r.srv[:1], nil)
+	select {
+	case pairs := <-ch:
+		if len(pairs) != 1 {
+			t.Fatalf("expected 1 instance, got %d", len(pairs))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dns change not pushed")
+	}
+
+	// 结果不变时不推送
+	select {
+	case pairs := <-ch:
+		t.Fatalf("unexpected push %v", pairs)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDNSDiscoveryA(t *testing.T) {
+	r := &stubResolver{ips: []net.IPAddr{{IP: net.ParseIP("10.0.0.2")}, {IP: net.ParseIP("fd00::1")}}}
+	d, err := NewDNSDiscovery("/services/user", "user.svc:9000", WithDNSResolver(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	pairs := d.GetServices()
+	if len(pairs) != 2 || pairs[0].Key != "/services/user/10.0.0.2:9000" || pairs[1].Key != "/services/user/[fd00::1]:9000" {
+		t.Fatalf("unexpected instances %v", pairs)
+	}
+}
+
+func TestDNSDiscoverySRVFallbackTier(t *testing.T) {
+	instances := srvInstances([]*net.SRV{
+		{Target: ".", Port: 8080, Priority: 10, Weight: 5},
+		{Target: "b.user.svc.", Port: 0, Priority: 10, Weight: 5},
+		{Target: "c.user.svc.", Port: 8080, Priority: 20, Weight: 3},
+		{Target: "d.user.svc.", Port: 8080, Priority: 30, Weight: 3},
+	})
+	// 最小优先级没有可用目标时使用下一优先级
+	if len(instances) != 1 || instances[0].Address != "c.user.svc:8080" || instances[0].Weight() != 3 {
+		t.Fatalf("unexpected instances %+v", instances)
+	}
+}
+
+func TestDNSDiscoveryErrors(t *testing.T) {
+	r := &stubResolver{srv: []*net.SRV{{Target: "a.user.svc.", Port: 8080, Weight: 1}}}
+	d, err := NewDNSDiscovery("/services/user", "_grpc._tcp.user.svc", WithDNSResolver(r), WithDNSRefreshInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ch := d.WatchService()
+	<-ch
+
+	// 临时错误保留上一次的实例列表
+	r.set(nil, &net.DNSError{Err: "timeout", Name: "_grpc._tcp.user.svc", IsTimeout: true, IsTemporary: true})
+	select {
+	case pairs := <-ch:
+		t.Fatalf("temporary error should keep instances, got %v", pairs)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if d.Err() == nil {
+		t.Fatal("temporary error should be reported")
+	}
+
+	// 记录不存在视为实例为空
+	r.set(nil, &net.DNSError{Err: "no such host", Name: "_grpc._tcp.user.svc", IsNotFound: true})
+	select {
+	case pairs := <-ch:
+		if len(pairs) != 0 {
+			t.Fatalf("expected empty instances, got %v", pairs)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("not found not pushed as empty instances")
+	}
+	if d.Err() != nil {
+		t.Fatalf("not found should not be reported as error, got %v", d.Err())
+	}
+}
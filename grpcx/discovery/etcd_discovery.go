@@ -5,6 +5,7 @@ import (
 	"github.com/liweiming-nova/common/etcd"
 	"github.com/liweiming-nova/common/xlog"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -12,50 +13,50 @@ import (
 
 type EtcdDiscovery struct {
 	client      *clientv3.Client
+	clientName  string // etcd 客户端名称，对应 [etcd.<name>]
 	servicePath string
 	filter      ServiceDiscoveryFilter
 	mu          sync.RWMutex
 	watchers    map[chan []*KVPair]context.CancelFunc
 	watchersMu  sync.RWMutex
+	fetchErr
 }
 
-func NewEtcdDiscovery(servicePath string) (*EtcdDiscovery, error) {
-	cli := etcd.Get()
+// EtcdDiscoveryOption EtcdDiscovery 选项
+type EtcdDiscoveryOption func(d *EtcdDiscovery)
+
+// WithEtcdClient 使用 [etcd.<name>] 对应的客户端，默认使用 [etcd] 顶层配置
+func WithEtcdClient(name string) EtcdDiscoveryOption {
+	return func(d *EtcdDiscovery) {
+		d.clientName = name
+	}
+}
+
+func NewEtcdDiscovery(servicePath string, opts ...EtcdDiscoveryOption) (*EtcdDiscovery, error) {
 	d := &EtcdDiscovery{
-		client:      cli.DefaultClient(),
+		clientName:  etcd.DefaultName,
 		servicePath: servicePath,
 		watchers:    make(map[chan []*KVPair]context.CancelFunc),
 	}
-
+	for _, opt := range opts {
+		opt(d)
+	}
+	cli, err := etcd.Named(d.clientName)
+	if err != nil {
+		return nil, err
+	}
+	d.client = cli.DefaultClient()
 	return d, nil
 }
 
 func (d *EtcdDiscovery) GetServices() []*KVPair {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	resp, err := d.client.Get(ctx, d.servicePath, clientv3.WithPrefix())
+	state, _, err := d.sync(context.Background())
+	d.fetchErr.set(err)
 	if err != nil {
 		xlog.Errorf(context.Background(), "EtcdDiscovery GetServices error: %v", err)
 		return nil
 	}
-
-	var pairs []*KVPair
-	for _, kv := range resp.Kvs {
-		value := strings.TrimSpace(string(kv.Value))
-		if value == "" {
-			continue
-		}
-		pair := &KVPair{
-			Key:   string(kv.Key),
-			Value: value,
-		}
-		if d.filter == nil || d.filter(pair) {
-			pairs = append(pairs, pair)
-		}
-	}
-
-	return pairs
+	return d.pairs(state)
 }
 
 func (d *EtcdDiscovery) WatchService() chan []*KVPair {
@@ -72,6 +73,13 @@ func (d *EtcdDiscovery) WatchService() chan []*KVPair {
 	return ch
 }
 
+const (
+	minWatchBackoff = 100 * time.Millisecond
+	maxWatchBackoff = 10 * time.Second
+)
+
+// watch 全量同步一次后按 revision 增量应用 PUT/DELETE 事件，实例集合变化（包括变为空）时推送；
+// watch 出错后从最近的 revision 恢复，revision 已被压缩时重新全量同步
 func (d *EtcdDiscovery) watch(ctx context.Context, ch chan []*KVPair) {
 	defer func() {
 		d.watchersMu.Lock()
@@ -80,36 +88,129 @@ func (d *EtcdDiscovery) watch(ctx context.Context, ch chan []*KVPair) {
 		close(ch)
 	}()
 
-	watchCh := d.client.Watch(ctx, d.servicePath, clientv3.WithPrefix())
-
-	// 初始推送一次
-	if pairs := d.GetServices(); len(pairs) > 0 {
+	var (
+		state   map[string]string // key -> value
+		rev     int64             // 已应用的 revision，0 表示需要全量同步
+		last    []*KVPair
+		pushed  bool
+		backoff = minWatchBackoff
+	)
+	push := func() bool {
+		pairs := d.pairs(state)
+		if pushed && equalPairs(last, pairs) {
+			return true
+		}
 		select {
 		case ch <- pairs:
+			last, pushed = pairs, true
+			return true
 		case <-ctx.Done():
-			return
+			return false
+		}
+	}
+	sleep := func() bool {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		if backoff *= 2; backoff > maxWatchBackoff {
+			backoff = maxWatchBackoff
 		}
+		return true
 	}
 
 	for {
-		select {
-		case wresp := <-watchCh:
-			if wresp.Err() != nil {
+		if rev == 0 {
+			var err error
+			state, rev, err = d.sync(ctx)
+			d.fetchErr.set(err)
+			if err != nil {
+				xlog.Errorf(context.Background(), "EtcdDiscovery sync %s error: %v", d.servicePath, err)
+				if !sleep() {
+					return
+				}
 				continue
 			}
-			if pairs := d.GetServices(); len(pairs) > 0 {
-				select {
-				case ch <- pairs:
-				case <-ctx.Done():
-					return
+			if !push() {
+				return
+			}
+		}
+
+		// 每轮 watch 使用独立的 ctx，中断（压缩、出错）后取消，避免底层 watcher 累积
+		wctx, wcancel := context.WithCancel(ctx)
+		watchCh := d.client.Watch(clientv3.WithRequireLeader(wctx), d.servicePath, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		for wresp := range watchCh {
+			if wresp.CompactRevision != 0 {
+				xlog.Warnf(context.Background(), "EtcdDiscovery watch %s compacted at %d, resync", d.servicePath, wresp.CompactRevision)
+				rev = 0
+				break
+			}
+			if err := wresp.Err(); err != nil {
+				xlog.Warnf(context.Background(), "EtcdDiscovery watch %s error, resume from %d: %v", d.servicePath, rev, err)
+				break
+			}
+			for _, ev := range wresp.Events {
+				key, value := string(ev.Kv.Key), strings.TrimSpace(string(ev.Kv.Value))
+				if ev.Type == clientv3.EventTypeDelete || value == "" {
+					delete(state, key)
+				} else {
+					state[key] = value
 				}
 			}
-		case <-ctx.Done():
+			if wresp.Header.Revision > rev {
+				rev = wresp.Header.Revision
+			}
+			backoff = minWatchBackoff
+			if !push() {
+				wcancel()
+				return
+			}
+		}
+		wcancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if !sleep() {
 			return
 		}
 	}
 }
 
+// sync 全量读取实例，返回 key -> value 及读取时的 revision
+func (d *EtcdDiscovery) sync(ctx context.Context) (map[string]string, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := d.client.Get(ctx, d.servicePath, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	state := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if value := strings.TrimSpace(string(kv.Value)); value != "" {
+			state[string(kv.Key)] = value
+		}
+	}
+	return state, resp.Header.Revision, nil
+}
+
+// pairs 将实例集合转换为按 key 排序、经过过滤的列表
+func (d *EtcdDiscovery) pairs(state map[string]string) []*KVPair {
+	d.mu.RLock()
+	filter := d.filter
+	d.mu.RUnlock()
+
+	pairs := make([]*KVPair, 0, len(state))
+	for key, value := range state {
+		pair := &KVPair{Key: key, Value: value}
+		if filter == nil || filter(pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
 func (d *EtcdDiscovery) RemoveWatcher(ch chan []*KVPair) {
 	d.watchersMu.Lock()
 	if cancel, ok := d.watchers[ch]; ok {
@@ -120,24 +221,25 @@ func (d *EtcdDiscovery) RemoveWatcher(ch chan []*KVPair) {
 }
 
 func (d *EtcdDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
-	return NewEtcdDiscovery(
-		servicePath,
-	)
+	return NewEtcdDiscovery(servicePath, WithEtcdClient(d.clientName))
 }
 
 func (d *EtcdDiscovery) SetFilter(filter ServiceDiscoveryFilter) {
+	d.mu.Lock()
 	d.filter = filter
+	d.mu.Unlock()
 }
 
+// Close 停止所有订阅，etcd 客户端为进程内共享，不在此关闭
 func (d *EtcdDiscovery) Close() {
 	d.watchersMu.Lock()
 	for _, cancel := range d.watchers {
 		cancel()
 	}
-	d.watchers = nil
 	d.watchersMu.Unlock()
+}
 
-	if d.client != nil {
-		d.client.Close()
-	}
+// ServicePath 返回服务路径
+func (d *EtcdDiscovery) ServicePath() string {
+	return d.servicePath
 }
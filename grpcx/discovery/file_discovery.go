@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// FileDiscovery 从 JSON/TOML 文件读取实例列表，按修改时间轮询文件并推送变更
+//
+// 文件格式（TOML）：
+//
+//	[[instances]]
+//	address = "127.0.0.1:8080"
+//	score = 2
+//	[instances.metadata]
+//	zone = "az1"
+//
+// JSON 支持 {"instances": [...]} 或直接使用数组
+type FileDiscovery struct {
+	*MemoryDiscovery
+
+	file     string
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	fetchErr
+}
+
+type instanceFile struct {
+	Instances []*instance.ServiceInstance `json:"instances" toml:"instances"`
+}
+
+// NewFileDiscovery 创建文件服务发现，interval <= 0 时默认 5s 检查一次文件
+func NewFileDiscovery(servicePath, file string, interval time.Duration) (*FileDiscovery, error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	d := &FileDiscovery{
+		MemoryDiscovery: NewMemoryDiscovery(servicePath),
+		file:            file,
+		interval:        interval,
+		stop:            make(chan struct{}),
+	}
+	if _, err := d.reload(); err != nil {
+		return nil, err
+	}
+	go d.watch()
+	return d, nil
+}
+
+// watch 定时检查文件，变化时重新加载，解析失败保留上一次的实例列表
+func (d *FileDiscovery) watch() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if changed, err := d.reload(); err != nil {
+				xlog.Errorf(context.Background(), "FileDiscovery reload %s error: %v", d.file, err)
+			} else if changed {
+				xlog.Infof(context.Background(), "FileDiscovery reload %s, %d instances", d.file, len(d.GetServices()))
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// reload 文件修改时间或大小变化时重新读取，读取失败记录到 Err
+func (d *FileDiscovery) reload() (changed bool, err error) {
+	defer func() {
+		if err != nil {
+			d.fetchErr.set(err)
+		}
+	}()
+	info, err := os.Stat(d.file)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(d.modTime) && info.Size() == d.size {
+		d.fetchErr.set(nil)
+		return false, nil
+	}
+	instances, err := readInstanceFile(d.file)
+	if err != nil {
+		return false, err
+	}
+	pairs := make([]*KVPair, 0, len(instances))
+	for _, si := range instances {
+		if si == nil || si.Address == "" {
+			continue
+		}
+		value, err := instance.Encode(si)
+		if err != nil {
+			return false, err
+		}
+		pairs = append(pairs, &KVPair{Key: d.ServicePath() + "/" + si.Address, Value: string(value)})
+	}
+	d.modTime, d.size = info.ModTime(), info.Size()
+	d.fetchErr.set(nil)
+	d.Set(pairs)
+	return true, nil
+}
+
+func readInstanceFile(file string) ([]*instance.ServiceInstance, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var f instanceFile
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".toml":
+		if _, err = toml.Decode(string(data), &f); err != nil {
+			return nil, err
+		}
+	case ".json":
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &f.Instances)
+		} else {
+			err = json.Unmarshal(data, &f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported discovery file %s, want .json or .toml", file)
+	}
+	return f.Instances, nil
+}
+
+// Clone 使用同一文件创建指定路径的服务发现
+func (d *FileDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	return NewFileDiscovery(servicePath, d.file, d.interval)
+}
+
+// Close 停止文件检查并关闭所有订阅
+func (d *FileDiscovery) Close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.MemoryDiscovery.Close()
+}
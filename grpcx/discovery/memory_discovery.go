@@ -0,0 +1,181 @@
+package discovery
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryDiscovery 内存服务发现，支持通过代码增删实例，适用于测试与本地运行
+type MemoryDiscovery struct {
+	servicePath string
+
+	mu     sync.RWMutex
+	pairs  map[string]*KVPair // key -> pair
+	filter ServiceDiscoveryFilter
+
+	watchersMu sync.Mutex
+	watchers   map[chan []*KVPair]struct{}
+}
+
+// NewMemoryDiscovery 创建内存服务发现
+func NewMemoryDiscovery(servicePath string, pairs ...*KVPair) *MemoryDiscovery {
+	d := &MemoryDiscovery{
+		servicePath: servicePath,
+		pairs:       map[string]*KVPair{},
+		watchers:    map[chan []*KVPair]struct{}{},
+	}
+	for _, pair := range pairs {
+		d.pairs[pair.Key] = pair
+	}
+	return d
+}
+
+// Add 新增或更新实例
+func (d *MemoryDiscovery) Add(key, value string) {
+	d.mu.Lock()
+	d.pairs[key] = &KVPair{Key: key, Value: value}
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Remove 删除实例
+func (d *MemoryDiscovery) Remove(key string) {
+	d.mu.Lock()
+	delete(d.pairs, key)
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Set 整体替换实例列表
+func (d *MemoryDiscovery) Set(pairs []*KVPair) {
+	d.mu.Lock()
+	d.pairs = make(map[string]*KVPair, len(pairs))
+	for _, pair := range pairs {
+		d.pairs[pair.Key] = pair
+	}
+	d.mu.Unlock()
+	d.notify()
+}
+
+// update 实例集合与当前不同时整体替换并推送，返回是否变化
+func (d *MemoryDiscovery) update(pairs []*KVPair) bool {
+	d.mu.RLock()
+	changed := len(pairs) != len(d.pairs)
+	for _, pair := range pairs {
+		if cur, ok := d.pairs[pair.Key]; !ok || *cur != *pair {
+			changed = true
+			break
+		}
+	}
+	d.mu.RUnlock()
+	if changed {
+		d.Set(pairs)
+	}
+	return changed
+}
+
+// GetServices 返回按 key 排序并经过过滤的实例列表
+func (d *MemoryDiscovery) GetServices() []*KVPair {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	pairs := make([]*KVPair, 0, len(d.pairs))
+	for _, pair := range d.pairs {
+		if d.filter == nil || d.filter(pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
+func (d *MemoryDiscovery) WatchService() chan []*KVPair {
+	ch := make(chan []*KVPair, 10)
+	d.watchersMu.Lock()
+	d.watchers[ch] = struct{}{}
+	d.watchersMu.Unlock()
+
+	// 初始推送一次
+	if pairs := d.GetServices(); len(pairs) > 0 {
+		ch <- pairs
+	}
+	return ch
+}
+
+// notify 向所有订阅者推送最新列表，订阅者消费过慢时丢弃旧的推送
+func (d *MemoryDiscovery) notify() {
+	pairs := d.GetServices()
+	d.watchersMu.Lock()
+	defer d.watchersMu.Unlock()
+	for ch := range d.watchers {
+		for {
+			select {
+			case ch <- pairs:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+func (d *MemoryDiscovery) RemoveWatcher(ch chan []*KVPair) {
+	d.watchersMu.Lock()
+	defer d.watchersMu.Unlock()
+	if _, ok := d.watchers[ch]; ok {
+		delete(d.watchers, ch)
+		close(ch)
+	}
+}
+
+// Clone 返回指定路径的空内存服务发现
+func (d *MemoryDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	return NewMemoryDiscovery(servicePath), nil
+}
+
+func (d *MemoryDiscovery) SetFilter(filter ServiceDiscoveryFilter) {
+	d.mu.Lock()
+	d.filter = filter
+	d.mu.Unlock()
+}
+
+// Close 关闭所有订阅
+func (d *MemoryDiscovery) Close() {
+	d.watchersMu.Lock()
+	defer d.watchersMu.Unlock()
+	for ch := range d.watchers {
+		delete(d.watchers, ch)
+		close(ch)
+	}
+}
+
+// ServicePath 返回服务路径
+func (d *MemoryDiscovery) ServicePath() string {
+	return d.servicePath
+}
+
+// StaticDiscovery 由固定地址列表构成的服务发现，实例不会变化
+type StaticDiscovery struct {
+	*MemoryDiscovery
+}
+
+// NewStaticDiscovery 根据地址列表创建服务发现，value 为地址本身
+func NewStaticDiscovery(servicePath string, addrs []string) *StaticDiscovery {
+	pairs := make([]*KVPair, 0, len(addrs))
+	for _, addr := range addrs {
+		pairs = append(pairs, &KVPair{Key: servicePath + "/" + addr, Value: addr})
+	}
+	return &StaticDiscovery{MemoryDiscovery: NewMemoryDiscovery(servicePath, pairs...)}
+}
+
+// Clone 返回同一地址列表、不同路径的静态服务发现
+func (d *StaticDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	var addrs []string
+	for _, pair := range d.GetServices() {
+		addrs = append(addrs, pair.Value)
+	}
+	return NewStaticDiscovery(servicePath, addrs), nil
+}
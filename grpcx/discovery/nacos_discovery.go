@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// NacosDiscovery 定期从 Nacos 拉取健康实例，服务名取 servicePath 去掉 /services/ 前缀
+type NacosDiscovery struct {
+	*MemoryDiscovery
+
+	cfg     *registry.NacosCfg
+	client  *registry.NacosClient
+	service string
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	fetchErr
+}
+
+// NewNacosDiscovery 创建 Nacos 服务发现，首次查询失败时记录日志并在后台重试
+func NewNacosDiscovery(servicePath string, cfg *registry.NacosCfg) (*NacosDiscovery, error) {
+	client, err := registry.NewNacosClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &NacosDiscovery{
+		MemoryDiscovery: NewMemoryDiscovery(servicePath),
+		cfg:             cfg,
+		client:          client,
+		service:         serviceName(servicePath),
+		stop:            make(chan struct{}),
+	}
+	if err = d.refresh(); err != nil {
+		xlog.Errorf(context.Background(), "NacosDiscovery list %s error: %v", d.service, err)
+	}
+	go d.watch()
+	return d, nil
+}
+
+func (d *NacosDiscovery) watch() {
+	ticker := time.NewTicker(d.client.Cfg().PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.refresh(); err != nil {
+				xlog.Errorf(context.Background(), "NacosDiscovery list %s error: %v", d.service, err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// refresh 拉取一次实例，变化时推送；权重优先取 metadata 中的 score，其次取 Nacos 实例权重
+func (d *NacosDiscovery) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	hosts, err := d.client.ListInstances(ctx, d.service)
+	if err != nil {
+		d.fetchErr.set(err)
+		return err
+	}
+	pairs := make([]*KVPair, 0, len(hosts))
+	for _, h := range hosts {
+		address := net.JoinHostPort(h.IP, strconv.Itoa(h.Port))
+		si := instance.New(address, h.Metadata, 0)
+		if si.Score <= 0 && h.Weight > 0 {
+			si.Score = h.Weight
+		}
+		value, err := instance.Encode(si)
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, &KVPair{Key: d.ServicePath() + "/" + address, Value: string(value)})
+	}
+	d.fetchErr.set(nil)
+	d.update(pairs)
+	return nil
+}
+
+// Clone 使用同一配置创建指定路径的服务发现
+func (d *NacosDiscovery) Clone(servicePath string) (ServiceDiscovery, error) {
+	return NewNacosDiscovery(servicePath, d.cfg)
+}
+
+// Close 停止拉取并关闭所有订阅
+func (d *NacosDiscovery) Close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.MemoryDiscovery.Close()
+}
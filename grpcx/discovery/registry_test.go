@@ -0,0 +1,282 @@
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/register"
+	"github.com/liweiming-nova/common/grpcx/registry"
+)
+
+// consulStub 模拟 Consul agent 的注册、TTL 检查与阻塞查询
+type consulStub struct {
+	mu       sync.Mutex
+	index    uint64
+	changed  chan struct{}
+	services map[string]*registry.ConsulService
+	passing  map[string]bool
+}
+
+func newConsulStub() *consulStub {
+	return &consulStub{index: 1, changed: make(chan struct{}), services: map[string]*registry.ConsulService{}, passing: map[string]bool{}}
+}
+
+// bump 在持锁时调用
+func (s *consulStub) bump() {
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// reset 模拟 agent 重启，丢失所有实例
+func (s *consulStub) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = map[string]*registry.ConsulService{}
+	s.passing = map[string]bool{}
+	s.bump()
+}
+
+func (s *consulStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/v1/agent/service/register":
+		var svc registry.ConsulService
+		if err := json.NewDecoder(r.Body).Decode(&svc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.services[svc.ID] = &svc
+		s.bump()
+		s.mu.Unlock()
+	case strings.HasPrefix(path, "/v1/agent/check/pass/"):
+		id := strings.TrimPrefix(path, "/v1/agent/check/pass/service:")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.services[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if !s.passing[id] {
+			s.passing[id] = true
+			s.bump()
+		}
+	case strings.HasPrefix(path, "/v1/agent/service/deregister/"):
+		s.mu.Lock()
+		id := strings.TrimPrefix(path, "/v1/agent/service/deregister/")
+		delete(s.services, id)
+		delete(s.passing, id)
+		s.bump()
+		s.mu.Unlock()
+	case strings.HasPrefix(path, "/v1/health/service/"):
+		name := strings.TrimPrefix(path, "/v1/health/service/")
+		s.mu.Lock()
+		if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index == s.index {
+			changed := s.changed
+			s.mu.Unlock()
+			select {
+			case <-changed:
+			case <-time.After(time.Second):
+			}
+			s.mu.Lock()
+		}
+		var entries []*registry.ConsulServiceEntry
+		for id, svc := range s.services {
+			if svc.Name != name || !s.passing[id] {
+				continue
+			}
+			entry := &registry.ConsulServiceEntry{}
+			entry.Service.ID, entry.Service.Address, entry.Service.Port, entry.Service.Meta = svc.ID, svc.Address, svc.Port, svc.Meta
+			entries = append(entries, entry)
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// nacosStub 模拟 Nacos 的实例注册、心跳、查询与登录
+type nacosStub struct {
+	mu        sync.Mutex
+	instances map[string]*registry.NacosInstance
+}
+
+func (s *nacosStub) reset() {
+	s.mu.Lock()
+	s.instances = map[string]*registry.NacosInstance{}
+	s.mu.Unlock()
+}
+
+func (s *nacosStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if r.URL.Path == "/nacos/v1/auth/login" {
+		// 凭证只能出现在表单请求体中
+		if q.Get("password") != "" || r.PostFormValue("username") != "nacos" || r.PostFormValue("password") != "nacos" {
+			http.Error(w, "bad credentials", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": "token", "tokenTtl": 18000})
+		return
+	}
+	if q.Get("accessToken") != "token" {
+		http.Error(w, "unauthorized", http.StatusForbidden)
+		return
+	}
+
+	key := q.Get("ip") + ":" + q.Get("port")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path + " " + r.Method {
+	case "/nacos/v1/ns/instance POST":
+		port, _ := strconv.Atoi(q.Get("port"))
+		weight, _ := strconv.ParseFloat(q.Get("weight"), 64)
+		inst := &registry.NacosInstance{IP: q.Get("ip"), Port: port, Weight: weight, Healthy: true, Enabled: true}
+		_ = json.Unmarshal([]byte(q.Get("metadata")), &inst.Metadata)
+		s.instances[key] = inst
+		_, _ = w.Write([]byte("ok"))
+	case "/nacos/v1/ns/instance DELETE":
+		delete(s.instances, key)
+		_, _ = w.Write([]byte("ok"))
+	case "/nacos/v1/ns/instance/beat PUT":
+		code := 10200
+		if _, ok := s.instances[key]; !ok {
+			code = 20404
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int{"code": code})
+	case "/nacos/v1/ns/instance/list GET":
+		hosts := []*registry.NacosInstance{}
+		for _, inst := range s.instances {
+			hosts = append(hosts, inst)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"hosts": hosts})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *consulStub) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.services)
+}
+
+func (s *nacosStub) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.instances)
+}
+
+// eventually 等待 cond 成立
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// expectReregistered 模拟注册中心丢失实例，等待重新注册并被服务发现感知
+func expectReregistered(t *testing.T, d ServiceDiscovery, reset func(), count func() int) chan []*KVPair {
+	t.Helper()
+	reset()
+	eventually(t, func() bool { return count() == 1 })
+	eventually(t, func() bool { return len(d.GetServices()) == 1 })
+	ch := d.WatchService()
+	<-ch
+	return ch
+}
+
+// waitFor 从 ch 中读取直到出现 n 个实例
+func waitFor(t *testing.T, ch chan []*KVPair, n int) []*KVPair {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case pairs := <-ch:
+			if len(pairs) == n {
+				return pairs
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %d instances", n)
+		}
+	}
+}
+
+func TestConsul(t *testing.T) {
+	stub := newConsulStub()
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	cfg := &registry.ConsulCfg{Address: srv.URL, CheckTTL: 150 * time.Millisecond, WaitTime: time.Second}
+
+	reg := register.NewConsulRegister(cfg)
+	if err := reg.Register("user", "10.0.0.1:8080", map[string]string{instance.MetadataScore: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	d := NewConsulDiscovery("/services/user", cfg)
+	defer d.Close()
+	pairs := d.GetServices()
+	if len(pairs) != 1 || pairs[0].Key != "/services/user/10.0.0.1:8080" || instance.FromValue(pairs[0].Value).Weight() != 3 {
+		t.Fatalf("unexpected instances %v", pairs)
+	}
+
+	// agent 重启后心跳返回 404，自动重新注册
+	ch := expectReregistered(t, d, stub.reset, stub.count)
+
+	if err := reg.Unregister("user", "10.0.0.1:8080"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, ch, 0)
+}
+
+func TestNacos(t *testing.T) {
+	stub := &nacosStub{}
+	stub.reset()
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	// 第一个服务端不可用，自动切换
+	cfg := &registry.NacosCfg{
+		Servers:      []string{"127.0.0.1:1", srv.URL},
+		Username:     "nacos",
+		Password:     "nacos",
+		BeatInterval: 50 * time.Millisecond,
+		PollInterval: 50 * time.Millisecond,
+	}
+
+	reg, err := register.NewNacosRegister(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = reg.Register("user", "10.0.0.1:8080", map[string]string{instance.MetadataScore: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewNacosDiscovery("/services/user", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	pairs := d.GetServices()
+	if len(pairs) != 1 || instance.FromValue(pairs[0].Value).Weight() != 2 {
+		t.Fatalf("unexpected instances %v", pairs)
+	}
+
+	// 服务端丢失实例后心跳返回 20404，自动重新注册
+	ch := expectReregistered(t, d, stub.reset, stub.count)
+
+	if err = reg.Unregister("user", "10.0.0.1:8080"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, ch, 0)
+}
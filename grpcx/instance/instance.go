@@ -5,6 +5,13 @@ import (
 	"strconv"
 )
 
+// 注册时写入 Metadata 的保留 key
+const (
+	MetadataScore   = "score"   // 权重，选择器通过 Weight 读取
+	MetadataVersion = "version" // 服务版本
+	MetadataZone    = "zone"    // 所在可用区
+)
+
 // ServiceInstance 统一的服务实例信息
 type ServiceInstance struct {
 	Address      string            `json:"address"`
@@ -13,6 +20,17 @@ type ServiceInstance struct {
 	Score        float64           `json:"score,omitempty"`
 }
 
+// New 创建实例，Metadata 中的 score 同时写入 Score 字段
+func New(address string, metadata map[string]string, registeredAt int64) *ServiceInstance {
+	si := &ServiceInstance{Address: address, Metadata: metadata, RegisteredAt: registeredAt}
+	if v, ok := metadata[MetadataScore]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			si.Score = f
+		}
+	}
+	return si
+}
+
 // Encode 将实例编码为 JSON 字节
 func Encode(si *ServiceInstance) ([]byte, error) {
 	return json.Marshal(si)
@@ -35,21 +53,31 @@ func ExtractAddress(value string) string {
 	return value
 }
 
+// Weight 返回实例权重：优先 Score，其次 Metadata 中的 score，缺省返回 1
+func (si *ServiceInstance) Weight() float64 {
+	if si.Score > 0 {
+		return si.Score
+	}
+	if v, ok := si.Metadata[MetadataScore]; ok && v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			return f
+		}
+	}
+	return 1
+}
+
 // ExtractScore 从 JSON 中解析 score，缺省返回 1
 func ExtractScore(value string) float64 {
 	if si, err := Decode([]byte(value)); err == nil && si != nil {
-		if si.Score > 0 {
-			return si.Score
-		}
-		// 兼容 Metadata 中的字符串 score
-		if si.Metadata != nil {
-			if v, ok := si.Metadata["score"]; ok && v != "" {
-				// 尝试解析为 float64
-				if f, err2 := strconv.ParseFloat(v, 64); err2 == nil && f > 0 {
-					return f
-				}
-			}
-		}
+		return si.Weight()
 	}
 	return 1
 }
+
+// FromValue 解析注册值，非 JSON 时将原值作为地址
+func FromValue(value string) *ServiceInstance {
+	if si, err := Decode([]byte(value)); err == nil && si != nil && si.Address != "" {
+		return si
+	}
+	return &ServiceInstance{Address: value}
+}
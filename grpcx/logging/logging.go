@@ -0,0 +1,83 @@
+// Package logging gRPC 客户端与服务端日志拦截器共用的选项
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const defaultMaxLogLength = 1024
+
+// Options 请求/响应日志选项
+type Options struct {
+	EnableRequestLog  bool
+	EnableResponseLog bool
+	EnableErrorLog    bool
+	LogRequestArgs    bool
+	LogResponseResult bool
+	MaxLogLength      int
+}
+
+type Option func(*Options)
+
+// NewOptions 默认全部开启，MaxLogLength <= 0 时取 1024
+func NewOptions(options ...Option) Options {
+	o := Options{
+		EnableRequestLog:  true,
+		EnableResponseLog: true,
+		EnableErrorLog:    true,
+		LogRequestArgs:    true,
+		LogResponseResult: true,
+		MaxLogLength:      defaultMaxLogLength,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.MaxLogLength <= 0 {
+		o.MaxLogLength = defaultMaxLogLength
+	}
+	return o
+}
+
+func WithEnableRequestLog(enable bool) Option {
+	return func(o *Options) { o.EnableRequestLog = enable }
+}
+
+func WithEnableResponseLog(enable bool) Option {
+	return func(o *Options) { o.EnableResponseLog = enable }
+}
+
+func WithEnableErrorLog(enable bool) Option {
+	return func(o *Options) { o.EnableErrorLog = enable }
+}
+
+func WithLogRequestArgs(enable bool) Option {
+	return func(o *Options) { o.LogRequestArgs = enable }
+}
+
+func WithLogResponseResult(enable bool) Option {
+	return func(o *Options) { o.LogResponseResult = enable }
+}
+
+func WithMaxLogLength(length int) Option {
+	return func(o *Options) { o.MaxLogLength = length }
+}
+
+// Marshal 安全地序列化对象为 JSON 字符串，超过 MaxLogLength 时截断
+func (o *Options) Marshal(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return o.truncate(fmt.Sprintf("%+v", v))
+	}
+	return o.truncate(string(data))
+}
+
+func (o *Options) truncate(s string) string {
+	if len(s) <= o.MaxLogLength {
+		return s
+	}
+	return s[:o.MaxLogLength] + "..."
+}
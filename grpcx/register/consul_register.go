@@ -0,0 +1,133 @@
+package register
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// ConsulRegister 通过 Consul agent 注册服务，使用 TTL 检查上报存活，
+// agent 丢失实例（如重启）时重新注册
+type ConsulRegister struct {
+	client *registry.ConsulClient
+
+	mu      sync.Mutex
+	service *registry.ConsulService
+	cancel  context.CancelFunc
+
+	stateHolder
+}
+
+// NewConsulRegister 创建 Consul 注册实例
+func NewConsulRegister(cfg *registry.ConsulCfg) *ConsulRegister {
+	return &ConsulRegister{client: registry.NewConsulClient(cfg)}
+}
+
+// Register 注册服务并启动 TTL 上报，实例 ID 为 服务名-地址
+func (r *ConsulRegister) Register(serviceName string, address string, metadata map[string]string) error {
+	if serviceName == "" || address == "" {
+		return fmt.Errorf("service name and address cannot be empty")
+	}
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	cfg := r.client.Cfg()
+	id := serviceName + "-" + address
+	service := &registry.ConsulService{
+		ID:      id,
+		Name:    serviceName,
+		Address: host,
+		Port:    port,
+		Meta:    metadata,
+		Check: &registry.ConsulCheck{
+			CheckID:                        "service:" + id,
+			TTL:                            cfg.CheckTTL.String(),
+			DeregisterCriticalServiceAfter: cfg.DeregisterAfter.String(),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err = r.register(ctx, service); err != nil {
+		cancel()
+		return err
+	}
+	r.mu.Lock()
+	r.service = service
+	r.cancel = cancel
+	r.mu.Unlock()
+	r.setState(true)
+
+	go r.heartbeat(ctx, "ConsulRegister "+id, cfg.CheckTTL/3,
+		func(ctx context.Context) error { return r.pass(ctx, service.Check.CheckID) },
+		func(ctx context.Context) error { return r.register(ctx, r.current()) })
+
+	xlog.Infof(context.Background(), "ConsulRegister service registered: %s", id)
+	return nil
+}
+
+// register 注册并立即上报一次检查通过，避免实例在首个 TTL 周期内处于 critical
+func (r *ConsulRegister) register(ctx context.Context, service *registry.ConsulService) error {
+	if service == nil {
+		return fmt.Errorf("service not registered")
+	}
+	if err := r.do(ctx, func(ctx context.Context) error { return r.client.RegisterService(ctx, service) }); err != nil {
+		return fmt.Errorf("failed to register service: %w", err)
+	}
+	return r.pass(ctx, service.Check.CheckID)
+}
+
+func (r *ConsulRegister) pass(ctx context.Context, checkID string) error {
+	return r.do(ctx, func(ctx context.Context) error { return r.client.PassTTL(ctx, checkID) })
+}
+
+func (r *ConsulRegister) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return fn(ctx)
+}
+
+func (r *ConsulRegister) current() *registry.ConsulService {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.service
+}
+
+// UpdateMetadata 以相同 ID 重新注册，更新实例元数据
+func (r *ConsulRegister) UpdateMetadata(serviceName string, address string, metadata map[string]string) error {
+	r.mu.Lock()
+	if r.service == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("service %s not registered", serviceName)
+	}
+	service := *r.service
+	service.Meta = metadata
+	r.service = &service
+	r.mu.Unlock()
+
+	return r.do(context.Background(), func(ctx context.Context) error { return r.client.RegisterService(ctx, &service) })
+}
+
+// Unregister 停止 TTL 上报并注销服务
+func (r *ConsulRegister) Unregister(serviceName string, address string) error {
+	r.mu.Lock()
+	service, cancel := r.service, r.cancel
+	r.service, r.cancel = nil, nil
+	r.mu.Unlock()
+	if service == nil {
+		return nil
+	}
+	cancel()
+	r.setState(false)
+
+	if err := r.do(context.Background(), func(ctx context.Context) error { return r.client.DeregisterService(ctx, service.ID) }); err != nil {
+		return fmt.Errorf("failed to deregister service: %w", err)
+	}
+	xlog.Infof(context.Background(), "ConsulRegister service unregistered: %s", service.ID)
+	return nil
+}
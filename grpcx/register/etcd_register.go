@@ -4,28 +4,85 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/liweiming-nova/common/etcd"
 	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/xlog"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type EtcdRegister struct {
+	updateMu   sync.Mutex // 串行化 UpdateMetadata 的写入，保证 etcd 中最终为最新的值
+	mu         sync.Mutex
 	serviceKey string // 在 etcd 中的注册路径，如 /services/user-service/127.0.0.1:8080
+	value      string // 当前注册值，租约丢失后使用该值重新写入
 	leaseID    clientv3.LeaseID
 	cancel     context.CancelFunc
 	client     *clientv3.Client
+	clientName string // etcd 客户端名称，对应 [etcd.<name>]
+	leaseTTL   int64  // 租约 TTL（秒）
+
+	registeredAt int64
+	stateHolder
+}
+
+const (
+	defaultLeaseTTL = 15
+
+	// 租约丢失后重新注册的退避区间
+	minRecoverBackoff = 500 * time.Millisecond
+	maxRecoverBackoff = 30 * time.Second
+)
+
+// EtcdRegisterOption EtcdRegister 选项
+type EtcdRegisterOption func(r *EtcdRegister)
+
+// WithLeaseTTL 设置租约 TTL（秒），<= 0 时使用 etcd.lease_ttl
+func WithLeaseTTL(ttl int64) EtcdRegisterOption {
+	return func(r *EtcdRegister) {
+		if ttl > 0 {
+			r.leaseTTL = ttl
+		}
+	}
+}
+
+// WithEtcdClient 使用 [etcd.<name>] 对应的客户端，默认使用 [etcd] 顶层配置
+func WithEtcdClient(name string) EtcdRegisterOption {
+	return func(r *EtcdRegister) {
+		r.clientName = name
+	}
+}
+
+// WithStateListener 设置注册状态变化回调
+func WithStateListener(fn StateListener) EtcdRegisterOption {
+	return func(r *EtcdRegister) {
+		r.SetStateListener(fn)
+	}
 }
 
-// NewEtcdRegister 创建新实例，需后续调用 SetClient 初始化 client
-func NewEtcdRegister() *EtcdRegister {
-	return &EtcdRegister{
-		client: etcd.Get().DefaultClient(),
+// NewEtcdRegister 创建注册实例，默认使用 [etcd] 顶层配置的客户端，租约 TTL 默认取其 lease_ttl
+func NewEtcdRegister(opts ...EtcdRegisterOption) (*EtcdRegister, error) {
+	r := &EtcdRegister{clientName: etcd.DefaultName}
+	for _, opt := range opts {
+		opt(r)
 	}
+	cli, err := etcd.Named(r.clientName)
+	if err != nil {
+		return nil, err
+	}
+	r.client = cli.DefaultClient()
+	if r.leaseTTL <= 0 {
+		r.leaseTTL = cli.LeaseTTL()
+	}
+	if r.leaseTTL <= 0 {
+		r.leaseTTL = defaultLeaseTTL
+	}
+	return r, nil
 }
 
-// Register 将服务注册到 etcd
+// Register 将服务注册到 etcd，并在后台续约；租约丢失时按退避重新申请租约并写入
 // serviceName: 服务名，如 "user-service"
 // address: 服务地址，如 "127.0.0.1:8080"
 // metadata: 可选元数据，如 {"version": "v1.2", "env": "prod"}
@@ -37,68 +94,144 @@ func (r *EtcdRegister) Register(serviceName string, address string, metadata map
 
 	// 构造 key：/services/user-service/127.0.0.1:8080
 	serviceKey := fmt.Sprintf("/services/%s/%s", strings.TrimPrefix(serviceName, "/"), strings.TrimPrefix(address, "/"))
-	r.serviceKey = serviceKey
 
-	// 创建租约（默认 15 秒，自动续期）
-	lease := clientv3.NewLease(r.client)
-	ctx, cancel := context.WithCancel(context.Background())
-	r.cancel = cancel
+	// 构造 value：统一使用公共实例编码
+	registeredAt := time.Now().Unix()
+	valueBytes, err := instance.Encode(instance.New(address, metadata, registeredAt))
+	if err != nil {
+		return fmt.Errorf("failed to marshal service metadata: %w", err)
+	}
 
-	// 申请租约（TTL 单位：秒）
-	leaseResp, err := lease.Grant(ctx, 15)
+	ctx, cancel := context.WithCancel(context.Background())
+	leaseID, err := r.put(ctx, serviceKey, string(valueBytes))
 	if err != nil {
 		cancel()
-		return fmt.Errorf("failed to grant lease: %w", err)
+		return err
 	}
-	r.leaseID = leaseResp.ID
 
-	// 构造 value：统一使用公共实例编码
-	valueBytes, err := instance.Encode(&instance.ServiceInstance{
-		Address:      address,
-		Metadata:     metadata,
-		RegisteredAt: time.Now().Unix(),
-	})
+	r.mu.Lock()
+	r.serviceKey = serviceKey
+	r.value = string(valueBytes)
+	r.leaseID = leaseID
+	r.registeredAt = registeredAt
+	r.cancel = cancel
+	r.mu.Unlock()
+	r.setState(true)
+
+	// 启动租约续期协程
+	go r.keepAlive(ctx, leaseID)
+
+	xlog.Infof(context.Background(), "EtcdRegister service registered: %s -> %s", serviceKey, string(valueBytes))
+	return nil
+}
+
+// put 申请租约并写入 key，失败时撤销租约
+func (r *EtcdRegister) put(ctx context.Context, key, value string) (clientv3.LeaseID, error) {
+	grantCtx, grantCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer grantCancel()
+	leaseResp, err := r.client.Grant(grantCtx, r.leaseTTL)
 	if err != nil {
-		lease.Revoke(ctx, r.leaseID)
-		cancel()
-		return fmt.Errorf("failed to marshal service metadata: %w", err)
+		return 0, fmt.Errorf("failed to grant lease: %w", err)
 	}
 
-	// 使用租约创建键值对（自动续约）
 	putCtx, putCancel := context.WithTimeout(ctx, 5*time.Second)
 	defer putCancel()
-
-	_, err = r.client.Put(putCtx, serviceKey, string(valueBytes), clientv3.WithLease(r.leaseID))
-	if err != nil {
-		lease.Revoke(ctx, r.leaseID)
-		cancel()
-		return fmt.Errorf("failed to register service: %w", err)
+	if _, err = r.client.Put(putCtx, key, value, clientv3.WithLease(leaseResp.ID)); err != nil {
+		revokeCtx, revokeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer revokeCancel()
+		_, _ = r.client.Revoke(revokeCtx, leaseResp.ID)
+		return 0, fmt.Errorf("failed to register service: %w", err)
 	}
+	return leaseResp.ID, nil
+}
 
-	// 启动租约自动续期协程（关键！）
-	go func() {
-		kaChan, err := lease.KeepAlive(context.Background(), r.leaseID)
-		if err != nil {
-			fmt.Printf("[EtcdRegister] KeepAlive failed: %v\n", err)
+// keepAlive 续约直到 ctx 取消；续约 channel 关闭（租约过期、网络分区、etcd 重启）时重新注册
+func (r *EtcdRegister) keepAlive(ctx context.Context, leaseID clientv3.LeaseID) {
+	for {
+		kaChan, err := r.client.KeepAlive(ctx, leaseID)
+		if err == nil {
+			for range kaChan {
+				// 正常收到 keep-alive 响应
+			}
+		}
+		if ctx.Err() != nil {
 			return
 		}
 
-		for {
-			select {
-			case _, ok := <-kaChan:
-				if !ok {
-					fmt.Println("[EtcdRegister] KeepAlive channel closed")
-					return
-				}
-				// 正常收到 keep-alive 响应
-			case <-ctx.Done():
-				lease.Close() // 可选：关闭 lease
-				return
+		r.setState(false)
+		xlog.Warnf(context.Background(), "EtcdRegister lease %x of %s lost, re-registering: %v", leaseID, r.GetServiceKey(), err)
+		if leaseID = r.recover(ctx); leaseID == 0 {
+			return
+		}
+	}
+}
+
+// recover 按指数退避重新申请租约并写入当前注册值，ctx 取消时返回 0
+func (r *EtcdRegister) recover(ctx context.Context) clientv3.LeaseID {
+	backoff := minRecoverBackoff
+	for {
+		r.mu.Lock()
+		key, value := r.serviceKey, r.value
+		r.mu.Unlock()
+		if key == "" {
+			return 0
+		}
+
+		leaseID, err := r.put(ctx, key, value)
+		if err == nil {
+			r.mu.Lock()
+			if ctx.Err() != nil || r.serviceKey == "" {
+				// 并发注销：撤销刚申请的租约，避免实例残留
+				r.mu.Unlock()
+				revokeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_, _ = r.client.Revoke(revokeCtx, leaseID)
+				cancel()
+				return 0
 			}
+			r.leaseID = leaseID
+			r.mu.Unlock()
+			r.setState(true)
+			xlog.Infof(context.Background(), "EtcdRegister service re-registered: %s", key)
+			return leaseID
+		}
+		xlog.Errorf(context.Background(), "EtcdRegister re-register %s error, retry in %s: %v", key, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return 0
+		}
+		if backoff *= 2; backoff > maxRecoverBackoff {
+			backoff = maxRecoverBackoff
 		}
-	}()
+	}
+}
+
+// UpdateMetadata 使用当前租约覆盖写入实例信息，注册时间保持不变；写入 etcd 时不持有 r.mu，不阻塞续约恢复与注销
+func (r *EtcdRegister) UpdateMetadata(serviceName string, address string, metadata map[string]string) error {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+
+	r.mu.Lock()
+	if r.serviceKey == "" || r.leaseID == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("service %s not registered", serviceName)
+	}
+	valueBytes, err := instance.Encode(instance.New(address, metadata, r.registeredAt))
+	if err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("failed to marshal service metadata: %w", err)
+	}
+	// 先记录新值，即使写入失败，租约恢复时也会使用最新元数据
+	r.value = string(valueBytes)
+	key, value, leaseID := r.serviceKey, r.value, r.leaseID
+	r.mu.Unlock()
 
-	fmt.Printf("[EtcdRegister] Service registered: %s -> %s\n", serviceKey, string(valueBytes))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err = r.client.Put(ctx, key, value, clientv3.WithLease(leaseID)); err != nil {
+		return fmt.Errorf("failed to update service metadata: %w", err)
+	}
 	return nil
 }
 
@@ -108,43 +241,51 @@ func (r *EtcdRegister) Unregister(serviceName string, address string) error {
 		return fmt.Errorf("etcd client not initialized")
 	}
 
+	r.mu.Lock()
+	serviceKey, leaseID, cancelKeepAlive := r.serviceKey, r.leaseID, r.cancel
+	// 清理状态
+	r.serviceKey = ""
+	r.value = ""
+	r.leaseID = 0
+	r.cancel = nil
+	r.mu.Unlock()
+
 	// 如果没有注册过，直接返回
-	if r.serviceKey == "" {
+	if serviceKey == "" {
 		return nil
 	}
 
+	// 先停止续约与恢复协程，避免注销后被重新写入
+	if cancelKeepAlive != nil {
+		cancelKeepAlive()
+	}
+	r.setState(false)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// 先撤销租约（会自动删除所有关联的 key）
-	if r.leaseID != 0 {
-		_, err := r.client.Lease.Revoke(ctx, r.leaseID)
-		if err != nil {
+	// 撤销租约（会自动删除所有关联的 key）
+	if leaseID != 0 {
+		if _, err := r.client.Revoke(ctx, leaseID); err != nil {
 			return fmt.Errorf("failed to revoke lease: %w", err)
 		}
 	}
 
-	// 清理状态
-	r.serviceKey = ""
-	r.leaseID = 0
-
-	// 取消续约协程
-	if r.cancel != nil {
-		r.cancel()
-		r.cancel = nil
-	}
-
-	fmt.Printf("[EtcdRegister] Service unregistered: %s\n", r.serviceKey)
+	xlog.Infof(context.Background(), "EtcdRegister service unregistered: %s", serviceKey)
 	return nil
 }
 
 // GetServiceKey 获取当前注册的服务 key（用于调试）
 func (r *EtcdRegister) GetServiceKey() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.serviceKey
 }
 
 // Close 关闭资源（释放客户端？一般由外部管理）
 func (r *EtcdRegister) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if r.cancel != nil {
 		r.cancel()
 	}
@@ -0,0 +1,141 @@
+package register
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// NacosRegister 通过 Nacos Open API 注册临时实例，定期发送心跳，
+// 服务端丢失实例时重新注册
+type NacosRegister struct {
+	client *registry.NacosClient
+
+	mu       sync.Mutex
+	service  string
+	instance *registry.NacosInstance
+	cancel   context.CancelFunc
+
+	stateHolder
+}
+
+// NewNacosRegister 创建 Nacos 注册实例
+func NewNacosRegister(cfg *registry.NacosCfg) (*NacosRegister, error) {
+	client, err := registry.NewNacosClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &NacosRegister{client: client}, nil
+}
+
+// Register 注册实例并启动心跳，权重取 metadata 中的 score
+func (r *NacosRegister) Register(serviceName string, address string, metadata map[string]string) error {
+	if serviceName == "" || address == "" {
+		return fmt.Errorf("service name and address cannot be empty")
+	}
+	inst, err := nacosInstance(address, metadata)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err = r.do(ctx, func(ctx context.Context) error { return r.client.RegisterInstance(ctx, serviceName, inst) }); err != nil {
+		cancel()
+		return fmt.Errorf("failed to register service: %w", err)
+	}
+	r.mu.Lock()
+	r.service = serviceName
+	r.instance = inst
+	r.cancel = cancel
+	r.mu.Unlock()
+	r.setState(true)
+
+	go r.heartbeat(ctx, "NacosRegister "+serviceName+"/"+address, r.client.Cfg().BeatInterval,
+		func(ctx context.Context) error {
+			return r.withCurrent(ctx, func(ctx context.Context, inst *registry.NacosInstance) error {
+				return r.client.Beat(ctx, serviceName, inst)
+			})
+		},
+		func(ctx context.Context) error {
+			return r.withCurrent(ctx, func(ctx context.Context, inst *registry.NacosInstance) error {
+				return r.client.RegisterInstance(ctx, serviceName, inst)
+			})
+		})
+
+	xlog.Infof(context.Background(), "NacosRegister service registered: %s %s", serviceName, address)
+	return nil
+}
+
+func nacosInstance(address string, metadata map[string]string) (*registry.NacosInstance, error) {
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	return &registry.NacosInstance{
+		IP:       host,
+		Port:     port,
+		Weight:   instance.New(address, metadata, 0).Weight(),
+		Healthy:  true,
+		Enabled:  true,
+		Metadata: metadata,
+	}, nil
+}
+
+func (r *NacosRegister) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return fn(ctx)
+}
+
+// withCurrent 使用当前实例调用 fn，已注销时直接返回
+func (r *NacosRegister) withCurrent(ctx context.Context, fn func(ctx context.Context, inst *registry.NacosInstance) error) error {
+	r.mu.Lock()
+	inst := r.instance
+	r.mu.Unlock()
+	if inst == nil {
+		return nil
+	}
+	return r.do(ctx, func(ctx context.Context) error { return fn(ctx, inst) })
+}
+
+// UpdateMetadata 重新注册同一实例，更新元数据与权重
+func (r *NacosRegister) UpdateMetadata(serviceName string, address string, metadata map[string]string) error {
+	inst, err := nacosInstance(address, metadata)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	if r.instance == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("service %s not registered", serviceName)
+	}
+	r.instance = inst
+	r.mu.Unlock()
+
+	return r.do(context.Background(), func(ctx context.Context) error { return r.client.RegisterInstance(ctx, serviceName, inst) })
+}
+
+// Unregister 停止心跳并注销实例
+func (r *NacosRegister) Unregister(serviceName string, address string) error {
+	r.mu.Lock()
+	service, inst, cancel := r.service, r.instance, r.cancel
+	r.instance, r.cancel = nil, nil
+	r.mu.Unlock()
+	if inst == nil {
+		return nil
+	}
+	cancel()
+	r.setState(false)
+
+	if err := r.do(context.Background(), func(ctx context.Context) error { return r.client.DeregisterInstance(ctx, service, inst) }); err != nil {
+		return fmt.Errorf("failed to deregister service: %w", err)
+	}
+	xlog.Infof(context.Background(), "NacosRegister service unregistered: %s %s:%s", service, inst.IP, strconv.Itoa(inst.Port))
+	return nil
+}
@@ -3,4 +3,14 @@ package register
 type Register interface {
 	Register(serviceName string, address string, metadata map[string]string) error
 	Unregister(serviceName string, address string) error
+	// UpdateMetadata 更新已注册实例的元数据，不重新注册
+	UpdateMetadata(serviceName string, address string, metadata map[string]string) error
+}
+
+// StateListener 注册状态变化回调，registered 为 false 表示实例已从注册中心丢失，正在重新注册
+type StateListener func(registered bool)
+
+// StateNotifier 可选接口，支持上报注册状态的 Register 实现
+type StateNotifier interface {
+	SetStateListener(fn StateListener)
 }
@@ -0,0 +1,86 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// stateHolder 记录注册状态，变化时回调 StateListener
+type stateHolder struct {
+	stateMu    sync.Mutex
+	registered bool
+	listener   StateListener
+}
+
+// SetStateListener 设置注册状态变化回调，实现 StateNotifier
+func (s *stateHolder) SetStateListener(fn StateListener) {
+	s.stateMu.Lock()
+	s.listener = fn
+	s.stateMu.Unlock()
+}
+
+// Registered 返回实例当前是否已注册
+func (s *stateHolder) Registered() bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.registered
+}
+
+func (s *stateHolder) setState(registered bool) {
+	s.stateMu.Lock()
+	changed := s.registered != registered
+	s.registered = registered
+	listener := s.listener
+	s.stateMu.Unlock()
+	if changed && listener != nil {
+		listener(registered)
+	}
+}
+
+// heartbeat 每隔 interval 调用 beat 直到 ctx 取消；beat 返回 registry.ErrNotFound
+// （注册中心已丢失该实例）时调用 reregister 重新注册
+func (s *stateHolder) heartbeat(ctx context.Context, name string, interval time.Duration, beat, reregister func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		err := beat(ctx)
+		if errors.Is(err, registry.ErrNotFound) {
+			xlog.Warnf(context.Background(), "%s instance lost, re-registering", name)
+			err = reregister(ctx)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			xlog.Errorf(context.Background(), "%s heartbeat error: %v", name, err)
+		}
+		s.setState(err == nil)
+	}
+}
+
+// splitHostPort 拆分 host:port
+func splitHostPort(address string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %s: %w", address, err)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ConsulCfg Consul 注册中心配置
+type ConsulCfg struct {
+	Address         string        `toml:"address"`          // agent 地址，如 127.0.0.1:8500 或 https://consul:8501，默认 127.0.0.1:8500
+	Token           string        `toml:"token"`            // ACL token
+	Datacenter      string        `toml:"datacenter"`       // 数据中心，默认 agent 所在数据中心
+	CheckTTL        time.Duration `toml:"check_ttl"`        // 注册时的 TTL 健康检查周期，按 1/3 周期上报，默认 10s
+	DeregisterAfter time.Duration `toml:"deregister_after"` // 检查失败多久后由 Consul 删除实例，默认 1m
+	WaitTime        time.Duration `toml:"wait_time"`        // 服务发现阻塞查询的最长等待时间，默认 30s
+}
+
+// ConsulService 注册到 agent 的服务
+type ConsulService struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *ConsulCheck      `json:"Check,omitempty"`
+}
+
+// ConsulCheck 服务健康检查
+type ConsulCheck struct {
+	CheckID                        string `json:"CheckID"`
+	TTL                            string `json:"TTL"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+// ConsulServiceEntry /v1/health/service 返回的实例
+type ConsulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+// ConsulClient Consul HTTP API 客户端，仅包含注册与服务发现用到的接口
+type ConsulClient struct {
+	cfg     ConsulCfg
+	baseURL string
+	http    *http.Client
+}
+
+// NewConsulClient 创建 Consul 客户端并填充默认值
+func NewConsulClient(cfg *ConsulCfg) *ConsulClient {
+	c := ConsulCfg{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Address == "" {
+		c.Address = "127.0.0.1:8500"
+	}
+	if c.CheckTTL <= 0 {
+		c.CheckTTL = 10 * time.Second
+	}
+	if c.DeregisterAfter <= 0 {
+		c.DeregisterAfter = time.Minute
+	}
+	if c.WaitTime <= 0 {
+		c.WaitTime = 30 * time.Second
+	}
+	return &ConsulClient{cfg: c, baseURL: baseURL(c.Address), http: &http.Client{}}
+}
+
+// Cfg 返回填充默认值后的配置
+func (c *ConsulClient) Cfg() ConsulCfg {
+	return c.cfg
+}
+
+// RegisterService 注册服务，同一 ID 重复注册即为更新
+func (c *ConsulClient) RegisterService(ctx context.Context, svc *ConsulService) error {
+	return c.do(ctx, http.MethodPut, "/v1/agent/service/register", nil, svc, nil, nil)
+}
+
+// DeregisterService 注销服务
+func (c *ConsulClient) DeregisterService(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(id), nil, nil, nil, nil)
+}
+
+// PassTTL 上报 TTL 检查通过，检查不存在（如 agent 重启）时返回 ErrNotFound
+func (c *ConsulClient) PassTTL(ctx context.Context, checkID string) error {
+	return c.do(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape(checkID), nil, nil, nil, nil)
+}
+
+// HealthService 查询通过健康检查的实例；index > 0 时为阻塞查询，直到数据变化或超过 wait_time
+func (c *ConsulClient) HealthService(ctx context.Context, name string, index uint64) ([]*ConsulServiceEntry, uint64, error) {
+	query := url.Values{"passing": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", c.cfg.WaitTime.String())
+	}
+	var entries []*ConsulServiceEntry
+	var header http.Header
+	if err := c.do(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(name), query, nil, &entries, &header); err != nil {
+		return nil, 0, err
+	}
+	newIndex, _ := strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+	return entries, newIndex, nil
+}
+
+func (c *ConsulClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}, header *http.Header) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.cfg.Datacenter != "" {
+		query.Set("dc", c.cfg.Datacenter)
+	}
+	headers := http.Header{}
+	if c.cfg.Token != "" {
+		headers.Set("X-Consul-Token", c.cfg.Token)
+	}
+	resp, err := doJSON(ctx, c.http, method, c.baseURL+path, query, headers, in, out)
+	if err != nil {
+		return fmt.Errorf("consul %s %s: %w", method, path, err)
+	}
+	if header != nil {
+		*header = resp.Header
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package registry 提供 Consul、Nacos 等基于 HTTP API 的注册中心客户端，
+// 供 register 与 discovery 包中的实现共用
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound 注册中心返回 404，例如实例或健康检查已不存在
+var ErrNotFound = errors.New("registry: not found")
+
+// baseURL 补全协议前缀并去掉末尾的 /
+func baseURL(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return strings.TrimRight(address, "/")
+}
+
+// doJSON 发送请求，in 为 url.Values 时按表单编码为请求体，其他非空值按 JSON 编码，out 非空时按 JSON 解码响应
+func doJSON(ctx context.Context, client *http.Client, method, rawURL string, query url.Values, header http.Header, in, out interface{}) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	if form, ok := in.(url.Values); ok {
+		body, contentType = strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"
+	} else if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp, ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err = json.Unmarshal(data, out); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	nacosDefaultGroup = "DEFAULT_GROUP"
+	// 心跳返回该 code 表示实例不存在，需要重新注册
+	nacosCodeNotFound = 20404
+)
+
+// NacosCfg Nacos 注册中心配置（使用 v1 Open API）
+type NacosCfg struct {
+	Servers      []string      `toml:"servers"`       // 服务端地址，如 127.0.0.1:8848，失败时依次尝试
+	NamespaceID  string        `toml:"namespace_id"`  // 命名空间，默认 public
+	Group        string        `toml:"group"`         // 分组，默认 DEFAULT_GROUP
+	Cluster      string        `toml:"cluster"`       // 集群名，默认 DEFAULT
+	Username     string        `toml:"username"`      // 开启鉴权时的用户名
+	Password     string        `toml:"password"`      // 开启鉴权时的密码
+	BeatInterval time.Duration `toml:"beat_interval"` // 临时实例心跳间隔，默认 5s
+	PollInterval time.Duration `toml:"poll_interval"` // 服务发现轮询间隔，默认 10s
+}
+
+// NacosInstance Nacos 实例
+type NacosInstance struct {
+	IP          string            `json:"ip"`
+	Port        int               `json:"port"`
+	Weight      float64           `json:"weight"`
+	Healthy     bool              `json:"healthy"`
+	Enabled     bool              `json:"enabled"`
+	ClusterName string            `json:"clusterName,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NacosClient Nacos HTTP API 客户端，仅包含注册与服务发现用到的接口
+type NacosClient struct {
+	cfg  NacosCfg
+	http *http.Client
+
+	mu          sync.Mutex
+	current     int // 当前使用的服务端下标
+	token       string
+	tokenExpire time.Time
+}
+
+// NewNacosClient 创建 Nacos 客户端并填充默认值
+func NewNacosClient(cfg *NacosCfg) (*NacosClient, error) {
+	c := NacosCfg{}
+	if cfg != nil {
+		c = *cfg
+	}
+	if len(c.Servers) == 0 {
+		return nil, errors.New("nacos servers cannot be empty")
+	}
+	if c.Group == "" {
+		c.Group = nacosDefaultGroup
+	}
+	if c.BeatInterval <= 0 {
+		c.BeatInterval = 5 * time.Second
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 10 * time.Second
+	}
+	return &NacosClient{cfg: c, http: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Cfg 返回填充默认值后的配置
+func (c *NacosClient) Cfg() NacosCfg {
+	return c.cfg
+}
+
+// RegisterInstance 注册临时实例，同一 ip:port 重复注册即为更新
+func (c *NacosClient) RegisterInstance(ctx context.Context, service string, inst *NacosInstance) error {
+	query := c.instanceQuery(service, inst)
+	query.Set("weight", strconv.FormatFloat(inst.Weight, 'f', -1, 64))
+	query.Set("enabled", "true")
+	query.Set("healthy", "true")
+	if len(inst.Metadata) > 0 {
+		md, _ := json.Marshal(inst.Metadata)
+		query.Set("metadata", string(md))
+	}
+	return c.do(ctx, http.MethodPost, "/nacos/v1/ns/instance", query, nil)
+}
+
+// DeregisterInstance 注销实例
+func (c *NacosClient) DeregisterInstance(ctx context.Context, service string, inst *NacosInstance) error {
+	return c.do(ctx, http.MethodDelete, "/nacos/v1/ns/instance", c.instanceQuery(service, inst), nil)
+}
+
+// Beat 发送心跳，实例已不存在（如服务端重启）时返回 ErrNotFound
+func (c *NacosClient) Beat(ctx context.Context, service string, inst *NacosInstance) error {
+	beat, _ := json.Marshal(map[string]interface{}{
+		"serviceName": c.cfg.Group + "@@" + service,
+		"ip":          inst.IP,
+		"port":        inst.Port,
+		"weight":      inst.Weight,
+		"cluster":     c.cluster(),
+		"metadata":    inst.Metadata,
+	})
+	query := c.instanceQuery(service, inst)
+	query.Set("beat", string(beat))
+	var resp struct {
+		Code int `json:"code"`
+	}
+	if err := c.do(ctx, http.MethodPut, "/nacos/v1/ns/instance/beat", query, &resp); err != nil {
+		return err
+	}
+	if resp.Code == nacosCodeNotFound {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListInstances 查询健康且启用的实例
+func (c *NacosClient) ListInstances(ctx context.Context, service string) ([]*NacosInstance, error) {
+	query := c.serviceQuery(service)
+	query.Set("healthyOnly", "true")
+	if c.cfg.Cluster != "" {
+		query.Set("clusters", c.cfg.Cluster)
+	}
+	var resp struct {
+		Hosts []*NacosInstance `json:"hosts"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/nacos/v1/ns/instance/list", query, &resp); err != nil {
+		return nil, err
+	}
+	hosts := resp.Hosts[:0]
+	for _, h := range resp.Hosts {
+		if h.Healthy && h.Enabled {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts, nil
+}
+
+func (c *NacosClient) cluster() string {
+	if c.cfg.Cluster == "" {
+		return "DEFAULT"
+	}
+	return c.cfg.Cluster
+}
+
+func (c *NacosClient) serviceQuery(service string) url.Values {
+	query := url.Values{"serviceName": {service}, "groupName": {c.cfg.Group}}
+	if c.cfg.NamespaceID != "" {
+		query.Set("namespaceId", c.cfg.NamespaceID)
+	}
+	return query
+}
+
+func (c *NacosClient) instanceQuery(service string, inst *NacosInstance) url.Values {
+	query := c.serviceQuery(service)
+	query.Set("ip", inst.IP)
+	query.Set("port", strconv.Itoa(inst.Port))
+	query.Set("ephemeral", "true")
+	query.Set("clusterName", c.cluster())
+	return query
+}
+
+// do 依次尝试各服务端，网络错误或 5xx 时切换到下一个
+func (c *NacosClient) do(ctx context.Context, method, path string, query url.Values, out interface{}) error {
+	c.mu.Lock()
+	start := c.current
+	c.mu.Unlock()
+
+	var lastErr error
+	for i := 0; i < len(c.cfg.Servers); i++ {
+		idx := (start + i) % len(c.cfg.Servers)
+		server := baseURL(c.cfg.Servers[idx])
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		token, err := c.accessToken(ctx, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if token != "" {
+			q.Set("accessToken", token)
+		}
+		resp, err := doJSON(ctx, c.http, method, server+path, q, nil, nil, out)
+		if err == nil || errors.Is(err, ErrNotFound) || (resp != nil && resp.StatusCode < 500) {
+			if err == nil && idx != start {
+				c.mu.Lock()
+				c.current = idx
+				c.mu.Unlock()
+			}
+			if err != nil {
+				return fmt.Errorf("nacos %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("nacos %s %s: %w", method, path, lastErr)
+}
+
+// accessToken 开启鉴权时登录获取 token，在过期前复用
+func (c *NacosClient) accessToken(ctx context.Context, server string) (string, error) {
+	if c.cfg.Username == "" {
+		return "", nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpire) {
+		return c.token, nil
+	}
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+		TokenTTL    int64  `json:"tokenTtl"`
+	}
+	// 凭证放在表单请求体中，避免出现在代理与服务端的访问日志里
+	form := url.Values{"username": {c.cfg.Username}, "password": {c.cfg.Password}}
+	if _, err := doJSON(ctx, c.http, http.MethodPost, server+"/nacos/v1/auth/login", nil, nil, form, &resp); err != nil {
+		return "", fmt.Errorf("nacos login: %w", err)
+	}
+	c.token = resp.AccessToken
+	// 提前 10% 刷新
+	c.tokenExpire = time.Now().Add(time.Duration(resp.TokenTTL) * time.Second * 9 / 10)
+	return c.token, nil
+}
@@ -0,0 +1,211 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc/attributes"
+	_ "google.golang.org/grpc/health" // 启用客户端子连接健康检查
+	gresolver "google.golang.org/grpc/resolver"
+)
+
+// Scheme 注册到 gRPC 的解析器 scheme，用法：grpc.NewClient("etcd:///user_server")
+const Scheme = "etcd"
+
+// servicePrefix 服务注册路径前缀，与 register.EtcdRegister 保持一致
+const servicePrefix = "/services/"
+
+type instanceKey struct{}
+
+// instanceAttr 包装 ServiceInstance，实现 Equal 以便元数据不变时复用子连接
+type instanceAttr struct {
+	si *instance.ServiceInstance
+	kv *discovery.KVPair
+}
+
+func (a *instanceAttr) Equal(o interface{}) bool {
+	b, ok := o.(*instanceAttr)
+	if !ok || a == nil || b == nil {
+		return ok && a == b
+	}
+	return a.kv.Value == b.kv.Value
+}
+
+// InstanceFromAddress 从解析器地址中取出服务实例信息
+func InstanceFromAddress(addr gresolver.Address) (*instance.ServiceInstance, bool) {
+	if addr.Attributes == nil {
+		return nil, false
+	}
+	a, ok := addr.Attributes.Value(instanceKey{}).(*instanceAttr)
+	if !ok {
+		return nil, false
+	}
+	return a.si, true
+}
+
+// KVPairFromAddress 从解析器地址中取出服务发现原始键值对，供 Selector 使用
+func KVPairFromAddress(addr gresolver.Address) (*discovery.KVPair, bool) {
+	if addr.Attributes == nil {
+		return nil, false
+	}
+	a, ok := addr.Attributes.Value(instanceKey{}).(*instanceAttr)
+	if !ok {
+		return nil, false
+	}
+	return a.kv, true
+}
+
+// ServiceConfig 生成默认服务配置，policy 为负载均衡策略名，healthCheck 开启子连接健康检查
+// 用法：grpc.WithDefaultServiceConfig(resolver.ServiceConfig("round_robin", true))
+func ServiceConfig(policy string, healthCheck bool) string {
+	if policy == "" {
+		policy = "round_robin"
+	}
+	cfg := fmt.Sprintf(`{"loadBalancingConfig":[{%q:{}}]`, policy)
+	if healthCheck {
+		cfg += `,"healthCheckConfig":{"serviceName":""}`
+	}
+	return cfg + "}"
+}
+
+// DiscoveryFactory 根据服务路径创建服务发现，默认使用 etcd
+type DiscoveryFactory func(servicePath string) (discovery.ServiceDiscovery, error)
+
+type builder struct {
+	scheme    string
+	discovery DiscoveryFactory
+}
+
+// NewBuilder 创建解析器构建器，可用于以自定义 scheme 或服务发现注册
+func NewBuilder(scheme string, factory DiscoveryFactory) gresolver.Builder {
+	return &builder{scheme: scheme, discovery: factory}
+}
+
+func init() {
+	gresolver.Register(NewBuilder(Scheme, func(servicePath string) (discovery.ServiceDiscovery, error) {
+		return discovery.NewEtcdDiscovery(servicePath)
+	}))
+}
+
+func (b *builder) Scheme() string {
+	return b.scheme
+}
+
+// Build 支持 etcd:///user_server 与 etcd:///services/user_server 两种写法
+func (b *builder) Build(target gresolver.Target, cc gresolver.ClientConn, opts gresolver.BuildOptions) (gresolver.Resolver, error) {
+	name := strings.TrimPrefix(target.Endpoint(), "/")
+	name = strings.TrimPrefix(name, strings.TrimPrefix(servicePrefix, "/"))
+	if name == "" {
+		return nil, fmt.Errorf("resolver: empty service name in target %q", target.URL.String())
+	}
+
+	dis, err := b.discovery(servicePrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: build discovery for %s: %w", name, err)
+	}
+
+	r := &etcdResolver{
+		name:      name,
+		cc:        cc,
+		discovery: dis,
+		done:      make(chan struct{}),
+	}
+	r.update(dis.GetServices())
+	r.watchCh = dis.WatchService()
+	go r.watch()
+	return r, nil
+}
+
+type etcdResolver struct {
+	name      string
+	cc        gresolver.ClientConn
+	discovery discovery.ServiceDiscovery
+	watchCh   chan []*discovery.KVPair
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	last []gresolver.Address
+}
+
+func (r *etcdResolver) watch() {
+	for {
+		select {
+		case kvPairs, ok := <-r.watchCh:
+			if !ok {
+				return
+			}
+			r.update(kvPairs)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// update 将节点列表转换为 gRPC 地址并推送给 ClientConn
+func (r *etcdResolver) update(kvPairs []*discovery.KVPair) {
+	addrs := make([]gresolver.Address, 0, len(kvPairs))
+	for _, kv := range kvPairs {
+		if kv == nil {
+			continue
+		}
+		si, err := instance.Decode([]byte(kv.Value))
+		if err != nil || si.Address == "" {
+			// 兼容直接以地址作为 value 的注册方式
+			si = &instance.ServiceInstance{Address: kv.Value}
+		}
+		addrs = append(addrs, gresolver.Address{
+			Addr:       si.Address,
+			Attributes: attributes.New(instanceKey{}, &instanceAttr{si: si, kv: kv}),
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	if r.last != nil && reflect.DeepEqual(addrKeys(r.last), addrKeys(addrs)) {
+		return
+	}
+	r.last = addrs
+	if err := r.cc.UpdateState(gresolver.State{Addresses: addrs}); err != nil {
+		xlog.Warnf(context.Background(), "resolver %s update state error: %v", r.name, err)
+	}
+}
+
+// addrKeys 提取地址与原始值，用于判断节点列表是否变化
+func addrKeys(addrs []gresolver.Address) []string {
+	keys := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		kv, _ := KVPairFromAddress(addr)
+		if kv != nil {
+			keys = append(keys, kv.Key+"="+kv.Value)
+		} else {
+			keys = append(keys, addr.Addr)
+		}
+	}
+	return keys
+}
+
+// ResolveNow 主动从服务发现拉取一次最新节点
+func (r *etcdResolver) ResolveNow(gresolver.ResolveNowOptions) {
+	go r.update(r.discovery.GetServices())
+}
+
+func (r *etcdResolver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.discovery.RemoveWatcher(r.watchCh)
+		// 服务发现由 Build 创建，随解析器一起关闭
+		r.discovery.Close()
+	})
+}
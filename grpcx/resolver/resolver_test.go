@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/grpcx/discovery"
+	"github.com/liweiming-nova/common/grpcx/instance"
+	gresolver "google.golang.org/grpc/resolver"
+)
+
+// fakeClientConn 记录解析器推送的地址
+type fakeClientConn struct {
+	gresolver.ClientConn
+	states chan gresolver.State
+}
+
+func (c *fakeClientConn) UpdateState(state gresolver.State) error {
+	c.states <- state
+	return nil
+}
+
+func nextAddrs(t *testing.T, cc *fakeClientConn) []gresolver.Address {
+	t.Helper()
+	select {
+	case state := <-cc.states:
+		return state.Addresses
+	case <-time.After(5 * time.Second):
+		t.Fatal("no address update")
+		return nil
+	}
+}
+
+func TestResolver(t *testing.T) {
+	value := func(addr, version string) string {
+		b, err := instance.Encode(instance.New(addr, map[string]string{"version": version}, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	d := discovery.NewMemoryDiscovery("/services/user_server")
+	d.Add("/services/user_server/10.0.0.1:80", value("10.0.0.1:80", "v1"))
+
+	var servicePath string
+	b := NewBuilder("memtest", func(path string) (discovery.ServiceDiscovery, error) {
+		servicePath = path
+		return d, nil
+	})
+	target, _ := url.Parse("memtest:///user_server")
+	cc := &fakeClientConn{states: make(chan gresolver.State, 10)}
+	r, err := b.Build(gresolver.Target{URL: *target}, cc, gresolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if servicePath != "/services/user_server" {
+		t.Fatalf("unexpected service path %q", servicePath)
+	}
+
+	addrs := nextAddrs(t, cc)
+	if len(addrs) != 1 || addrs[0].Addr != "10.0.0.1:80" {
+		t.Fatalf("unexpected initial addresses %v", addrs)
+	}
+	if si, ok := InstanceFromAddress(addrs[0]); !ok || si.Metadata["version"] != "v1" {
+		t.Fatalf("instance metadata not attached: %+v", si)
+	}
+
+	// 新增、元数据变化、删除都推送到 ClientConn
+	d.Add("/services/user_server/10.0.0.2:80", value("10.0.0.2:80", "v1"))
+	if addrs = nextAddrs(t, cc); len(addrs) != 2 {
+		t.Fatalf("expected 2 addresses after add, got %v", addrs)
+	}
+	d.Add("/services/user_server/10.0.0.2:80", value("10.0.0.2:80", "v2"))
+	addrs = nextAddrs(t, cc)
+	for _, addr := range addrs {
+		if si, _ := InstanceFromAddress(addr); addr.Addr == "10.0.0.2:80" && si.Metadata["version"] != "v2" {
+			t.Fatalf("metadata update not pushed: %+v", si)
+		}
+	}
+	d.Remove("/services/user_server/10.0.0.1:80")
+	if addrs = nextAddrs(t, cc); len(addrs) != 1 || addrs[0].Addr != "10.0.0.2:80" {
+		t.Fatalf("unexpected addresses after remove %v", addrs)
+	}
+}
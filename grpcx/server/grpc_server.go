@@ -1,26 +1,66 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/liweiming-nova/common/auth"
+	"github.com/liweiming-nova/common/grpcx/instance"
 	"github.com/liweiming-nova/common/grpcx/register"
+	"github.com/liweiming-nova/common/grpcx/tlsx"
 	"github.com/liweiming-nova/common/utils"
+	"github.com/liweiming-nova/common/xlog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"net"
-	"strings"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const defaultDrainTimeout = 10 * time.Second
+
+// ErrUnknownRegister register 配置不是 etcd、consul、nacos 之一
+var ErrUnknownRegister = errors.New("unknown register")
+
 type GrpcServer struct {
 	cfg      *GrpcConfig
 	server   *grpc.Server
 	register register.Register
 	listener net.Listener
-	name     string
-	addr     string
-	started  bool
+	tls      *tlsx.Reloader
+	limiter  *Limiter
+	health   *health.Server
+	inflight int64 // 正在处理的 RPC 数，不含 backgroundStreams
+
+	drainOnce  sync.Once
+	drainClose sync.Once
+	draining   chan struct{}
+	name       string
+	addr       string
+	started    bool
+
+	mu       sync.Mutex
+	metadata map[string]string // 注册元数据
 }
 
 func NewGrpcServer(cfg *GrpcConfig, name string, registerFunc func(*grpc.Server), interceptors ...grpc.UnaryServerInterceptor) (r *GrpcServer, err error) {
+	return NewGrpcServerWithOptions(cfg, name, registerFunc, WithUnaryInterceptors(interceptors...))
+}
+
+// NewGrpcServerWithOptions 与 NewGrpcServer 相同，额外支持 stream 拦截器与原生 grpc.ServerOption
+func NewGrpcServerWithOptions(cfg *GrpcConfig, name string, registerFunc func(*grpc.Server), opts ...Option) (r *GrpcServer, err error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	r = &GrpcServer{
 		cfg:  cfg,
 		name: name,
@@ -28,35 +68,42 @@ func NewGrpcServer(cfg *GrpcConfig, name string, registerFunc func(*grpc.Server)
 	if r.name == "" {
 		r.name = "default"
 	}
+	if cfg.ServiceName != "" {
+		r.name = cfg.ServiceName
+	}
 
 	if cfg.Register == "" {
 		cfg.Register = "etcd"
 	}
+	cfg.warnDeprecated(r.name)
 
-	var lisIp, lisPort string
-	// 尝试获取本机ip 默认值
-	if lisIp, err = utils.GetLocalIP(); err != nil || len(lisIp) == 0 {
-		err = fmt.Errorf("ip parse fail, %s", err)
-	}
-	// 通过配置文件获取
-	if err == nil {
-		if t := strings.Split(cfg.DialAddr, ":"); len(t) != 2 || len(t[1]) == 0 {
-			err = fmt.Errorf("port parse fail")
-		} else {
-			lisPort = t[1]
-		}
-	}
-	if err != nil {
+	if r.addr, err = advertiseAddr(cfg); err != nil {
 		return
 	}
-
-	addr := fmt.Sprintf("%s:%s", lisIp, lisPort)
-	r.addr = addr
+	r.metadata = advertiseMetadata(cfg)
 
 	switch cfg.Register {
 	case "etcd":
-		r.register = register.NewEtcdRegister()
+		if r.register, err = register.NewEtcdRegister(register.WithLeaseTTL(cfg.EtcdLeaseTTL), register.WithEtcdClient(cfg.EtcdClient)); err != nil {
+			return
+		}
+	case "consul":
+		r.register = register.NewConsulRegister(cfg.Consul)
+	case "nacos":
+		if r.register, err = register.NewNacosRegister(cfg.Nacos); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("grpc server %s: %w %q", r.name, ErrUnknownRegister, cfg.Register)
+		return
+	}
+	// 实例从注册中心丢失期间健康检查返回 NOT_SERVING，重新注册后恢复
+	if notifier, ok := r.register.(register.StateNotifier); ok {
+		notifier.SetStateListener(r.onRegisterState)
 	}
+	// panic 恢复位于最外层，覆盖后续所有拦截器与 handler
+	interceptors := []grpc.UnaryServerInterceptor{RecoveryUnaryServerInterceptor, r.inflightUnaryInterceptor}
+	streamInterceptors := []grpc.StreamServerInterceptor{RecoveryStreamServerInterceptor, r.inflightStreamInterceptor}
 	// 添加日志插件
 	if cfg.EnableLogPlugin {
 		logInterceptor := NewLogInterceptor(
@@ -68,22 +115,190 @@ func NewGrpcServer(cfg *GrpcConfig, name string, registerFunc func(*grpc.Server)
 			WithMaxLogLength(cfg.LogMaxLength),
 		)
 		interceptors = append(interceptors, logInterceptor.UnaryServerInterceptor)
+		streamInterceptors = append(streamInterceptors, logInterceptor.StreamServerInterceptor)
 	}
+	// 认证与限流放在日志之后，便于记录被拒绝的请求；认证在限流之前，按认证后的身份区分调用方
+	if cfg.Auth.Enabled() {
+		var authenticator *auth.Authenticator
+		if authenticator, err = auth.New(cfg.Auth); err != nil {
+			return
+		}
+		interceptors = append(interceptors, authenticator.UnaryServerInterceptor)
+		streamInterceptors = append(streamInterceptors, authenticator.StreamServerInterceptor)
+	}
+	// 限流器始终创建以便配置热更新后生效
+	r.limiter = NewLimiter(cfg.Limit)
+	interceptors = append(interceptors, r.limiter.UnaryServerInterceptor)
+	streamInterceptors = append(streamInterceptors, r.limiter.StreamServerInterceptor)
+	// 业务拦截器位于认证之后，只处理已认证的请求并可读取 auth.FromContext
+	interceptors = append(interceptors, options.unaryInterceptors...)
+	streamInterceptors = append(streamInterceptors, options.streamInterceptors...)
 
-	// 使用 ChainUnaryInterceptor 支持多个拦截器
+	// 使用 ChainUnaryInterceptor/ChainStreamInterceptor 支持多个拦截器
 	var serverOptions []grpc.ServerOption
 	if len(interceptors) > 0 {
 		serverOptions = append(serverOptions, grpc.ChainUnaryInterceptor(interceptors...))
 	}
+	if len(streamInterceptors) > 0 {
+		serverOptions = append(serverOptions, grpc.ChainStreamInterceptor(streamInterceptors...))
+	}
+	serverOptions = append(serverOptions, transportOptions(cfg)...)
+	serverOptions = append(serverOptions, options.serverOptions...)
+
+	// TLS / mTLS
+	if cfg.TLS.Enabled() {
+		if r.tls, err = tlsx.NewReloader(cfg.TLS); err != nil {
+			return
+		}
+		var tlsConfig *tls.Config
+		if tlsConfig, err = r.tls.ServerConfig(); err != nil {
+			r.tls.Close()
+			return
+		}
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
 
 	// 创建 gRPC Server
 	r.server = grpc.NewServer(serverOptions...)
 
 	registerFunc(r.server)
 
+	// 健康检查：启动前各服务均为 NOT_SERVING，Start 后置为 SERVING
+	r.health = health.NewServer()
+	healthpb.RegisterHealthServer(r.server, r.health)
+	r.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	if cfg.EnableReflection {
+		reflection.Register(r.server)
+	}
+
+	return
+}
+
+// advertiseAddr 计算注册地址：advertise_host > advertise_interface > 本机第一个非回环 IPv4，端口默认取监听端口
+func advertiseAddr(cfg *GrpcConfig) (string, error) {
+	_, port, err := net.SplitHostPort(cfg.DialAddr)
+	if err != nil || port == "" {
+		return "", fmt.Errorf("port parse fail, %v", err)
+	}
+	if cfg.AdvertisePort > 0 {
+		port = strconv.Itoa(cfg.AdvertisePort)
+	}
+
+	host := cfg.AdvertiseHost
+	if host == "" {
+		if cfg.AdvertiseInterface != "" {
+			host, err = utils.GetInterfaceIP(cfg.AdvertiseInterface)
+		} else {
+			host, err = utils.GetLocalIP()
+		}
+		if err != nil || host == "" {
+			return "", fmt.Errorf("ip parse fail, %v", err)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// advertiseMetadata 由配置生成注册元数据
+func advertiseMetadata(cfg *GrpcConfig) map[string]string {
+	md := make(map[string]string, len(cfg.Tags)+3)
+	for k, v := range cfg.Tags {
+		md[k] = v
+	}
+	if cfg.Weight > 0 {
+		md[instance.MetadataScore] = strconv.FormatFloat(cfg.Weight, 'f', -1, 64)
+	}
+	if cfg.Version != "" {
+		md[instance.MetadataVersion] = cfg.Version
+	}
+	if cfg.Zone != "" {
+		md[instance.MetadataZone] = cfg.Zone
+	}
+	return md
+}
+
+func copyMetadata(md map[string]string) map[string]string {
+	r := make(map[string]string, len(md))
+	for k, v := range md {
+		r[k] = v
+	}
+	return r
+}
+
+// UpdateMetadata 合并更新注册元数据并同步到注册中心，值为空时删除该 key
+func (s *GrpcServer) UpdateMetadata(metadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range metadata {
+		if v == "" {
+			delete(s.metadata, k)
+		} else {
+			s.metadata[k] = v
+		}
+	}
+	if !s.started {
+		return nil
+	}
+	return s.register.UpdateMetadata(s.name, s.addr, copyMetadata(s.metadata))
+}
+
+// SetWeight 更新注册权重
+func (s *GrpcServer) SetWeight(weight float64) error {
+	return s.UpdateMetadata(map[string]string{instance.MetadataScore: strconv.FormatFloat(weight, 'f', -1, 64)})
+}
+
+// transportOptions 根据配置生成连接、消息大小与 keepalive 相关选项，未配置的项沿用 gRPC 默认值
+func transportOptions(cfg *GrpcConfig) (opts []grpc.ServerOption) {
+	if timeout := cfg.ConnectionTimeout; timeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(timeout))
+	}
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize))
+	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionIdle:     cfg.MaxConnectionIdle,
+		MaxConnectionAge:      cfg.MaxConnectionAge,
+		MaxConnectionAgeGrace: cfg.MaxConnectionAgeGrace,
+		Time:                  cfg.KeepaliveTime,
+		Timeout:               cfg.KeepaliveTimeout,
+	}))
+	if cfg.KeepaliveMinTime > 0 || cfg.KeepalivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.KeepaliveMinTime,
+			PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		}))
+	}
 	return
 }
 
+// setServingStatus 设置整体（空服务名）及所有已注册服务的健康状态
+func (s *GrpcServer) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	s.health.SetServingStatus("", status)
+	for service := range s.server.GetServiceInfo() {
+		s.health.SetServingStatus(service, status)
+	}
+}
+
+// onRegisterState 注册状态变化时同步健康状态，Stop 后 health 已 Shutdown，不会被重新置为 SERVING
+func (s *GrpcServer) onRegisterState(registered bool) {
+	if registered {
+		s.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		return
+	}
+	xlog.Warnf(context.Background(), "gRPC Server [%s] is not registered, set health to NOT_SERVING", s.name)
+	s.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// HealthServer 返回健康检查服务，可用于按服务调整状态
+func (s *GrpcServer) HealthServer() *health.Server {
+	return s.health
+}
+
 // Start 启动 gRPC 服务（不带额外注册）
 func (s *GrpcServer) Start() error {
 	if s.started {
@@ -107,20 +322,123 @@ func (s *GrpcServer) Start() error {
 		}
 	}()
 
-	err = s.register.Register(s.name, s.addr, make(map[string]string))
+	// 注册成功后才置为 SERVING，注册失败时保持 NOT_SERVING 并停止服务，避免未注册的实例对外提供服务
+	s.mu.Lock()
+	err = s.register.Register(s.name, s.addr, copyMetadata(s.metadata))
+	s.mu.Unlock()
 	if err != nil {
+		s.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		// Serve 可能尚未开始，直接关闭监听，避免注册失败后端口仍接受连接
+		s.server.Stop()
+		listener.Close()
 		return fmt.Errorf("failed to register gRPC Server [%s]: %w", s.name, err)
 	}
+	s.setServingStatus(healthpb.HealthCheckResponse_SERVING)
 
 	return nil
 }
 
+// updateCfg 应用热更新后的配置，目前仅限流配置即时生效，限流配置未变时令牌桶不会重置
+func (s *GrpcServer) updateCfg(cfg *GrpcConfig) {
+	s.limiter.Update(cfg.Limit)
+}
+
+// ForcedStopError 排空超时后强制停止时返回，Inflight 为被中断的 RPC 数
+type ForcedStopError struct {
+	Inflight int64
+}
+
+func (e *ForcedStopError) Error() string {
+	return fmt.Sprintf("grpc server drain timeout, %d rpcs still in flight were forced to stop", e.Inflight)
+}
+
+func (s *GrpcServer) inflightUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	atomic.AddInt64(&s.inflight, 1)
+	defer atomic.AddInt64(&s.inflight, -1)
+	return handler(ctx, req)
+}
+
+// backgroundStreams 长期存在、不代表业务请求的流，不计入在途数，停止时主动结束
+var backgroundStreams = map[string]bool{
+	"/grpc.health.v1.Health/Watch":                                   true,
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo":      true,
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": true,
+}
+
+func (s *GrpcServer) inflightStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if backgroundStreams[info.FullMethod] {
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-s.drainCh():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+	atomic.AddInt64(&s.inflight, 1)
+	defer atomic.AddInt64(&s.inflight, -1)
+	return handler(srv, ss)
+}
+
+// drainCh 开始排空时关闭
+func (s *GrpcServer) drainCh() chan struct{} {
+	s.drainOnce.Do(func() { s.draining = make(chan struct{}) })
+	return s.draining
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// Stop 优雅停止：置为 NOT_SERVING -> 注销 -> 等待服务发现传播 -> 在排空超时内 GracefulStop -> 超时后强制 Stop
+// 强制停止时返回 *ForcedStopError
 func (s *GrpcServer) Stop() error {
-	err := s.register.Unregister(s.name, s.addr)
-	if err != nil {
-		return err
+	// 先置为 NOT_SERVING，健康探测与 health watch 的客户端在注销前即可摘除该节点
+	s.health.Shutdown()
+
+	// 注销失败也继续停止，避免进程退出时服务残留
+	unregisterErr := s.register.Unregister(s.name, s.addr)
+	if unregisterErr != nil {
+		unregisterErr = fmt.Errorf("failed to unregister gRPC Server [%s]: %w", s.name, unregisterErr)
 	}
-	s.server.Stop()
 
-	return nil
+	if s.cfg.PropagationDelay > 0 {
+		time.Sleep(s.cfg.PropagationDelay)
+	}
+	// 结束 health watch 与 reflection 流，否则 GracefulStop 总要等到排空超时
+	s.drainClose.Do(func() { close(s.drainCh()) })
+
+	drainTimeout := s.cfg.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	var forceErr error
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		forceErr = &ForcedStopError{Inflight: atomic.LoadInt64(&s.inflight)}
+		s.server.Stop()
+		<-done
+	}
+	if s.tls != nil {
+		s.tls.Close()
+	}
+
+	return errors.Join(unregisterErr, forceErr)
 }
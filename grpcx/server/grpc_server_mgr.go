@@ -1,9 +1,15 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"github.com/liweiming-nova/common/auth"
 	"github.com/liweiming-nova/common/config"
+	"github.com/liweiming-nova/common/config/options"
+	"github.com/liweiming-nova/common/grpcx/registry"
+	"github.com/liweiming-nova/common/grpcx/tlsx"
 	"github.com/liweiming-nova/common/utils"
+	"github.com/liweiming-nova/common/xlog"
 	"google.golang.org/grpc"
 	"sync"
 	"time"
@@ -26,18 +32,18 @@ type rpcConfig struct {
 }
 
 type GrpcConfig struct {
-	DialAddr         string        `toml:"addr"`
+	DialAddr          string        `toml:"addr"`
+	ConnectionTimeout time.Duration `toml:"connection_timeout"` // 新连接完成握手（含 TLS）的最长时间，默认 120s
+
+	// Deprecated: gRPC 没有连接级读写超时，read_timeout/write_timeout 不再生效；握手超时请改用 connection_timeout，
+	// 请求超时由调用方的 deadline 控制
 	DialReadTimeout  time.Duration `toml:"read_timeout"`
 	DialWriteTimeout time.Duration `toml:"write_timeout"`
-
-	// register
-	Register string `toml:"register"`
-	// zookeeper register
+	// Deprecated: 不再支持 zookeeper 注册，配置后仅输出警告
 	RegisterZkServers        []string      `toml:"register_zk_servers"`
 	RegisterZkBasePath       string        `toml:"register_zk_basepath"`
 	RegisterZkUpdateInterval time.Duration `toml:"register_zk_update_interval"`
-
-	// nacos register
+	// Deprecated: 请改用 register = "nacos" 与 [rpc.server.<name>.nacos]，以下字段不再生效
 	RegisterNcServers     []string `toml:"register_nc_servers"`
 	RegisterNcNamespaceId string   `toml:"register_nc_namespace_id"`
 	RegisterNcCacheDir    string   `toml:"register_nc_cache_dir"`
@@ -45,12 +51,41 @@ type GrpcConfig struct {
 	RegisterNcLogLevel    string   `toml:"register_nc_log_level"`
 	RegisterNcAccessKey   string   `toml:"register_nc_access_key"`
 	RegisterNcSecretKey   string   `toml:"register_nc_secret_key"`
-
-	// etcd register
+	// Deprecated: 请改用 [etcd] 或 [etcd.<name>] 配合 etcd_client，以下字段不再生效
 	EtcdEndpoints   []string      `toml:"etcd_endpoints"`
 	EtcdDialTimeout time.Duration `toml:"etcd_dial_timeout"`
-	EtcdLeaseTTL    int64         `toml:"etcd_lease_ttl"`
-	ServiceName     string        `toml:"service_name"`
+	// Deprecated: gRPC 固定使用 protobuf 编码，配置后仅输出警告
+	SerializeType string `toml:"serialize_type"`
+
+	// register
+	Register     string              `toml:"register"`       // 注册中心：etcd（默认）、consul、nacos
+	ServiceName  string              `toml:"service_name"`   // 注册到服务发现的名称，默认使用服务配置名
+	EtcdLeaseTTL int64               `toml:"etcd_lease_ttl"` // etcd 注册租约 TTL（秒），默认使用 etcd.lease_ttl
+	EtcdClient   string              `toml:"etcd_client"`    // 使用的 etcd 客户端名称，对应 [etcd.<name>]，默认 [etcd]
+	Consul       *registry.ConsulCfg `toml:"consul"`         // register = "consul" 时使用
+	Nacos        *registry.NacosCfg  `toml:"nacos"`          // register = "nacos" 时使用
+
+	// advertise
+	AdvertiseHost      string            `toml:"advertise_host"`      // 注册的主机地址，优先级最高
+	AdvertisePort      int               `toml:"advertise_port"`      // 注册的端口，默认取 addr 中的端口
+	AdvertiseInterface string            `toml:"advertise_interface"` // 取该网卡的 IPv4 作为注册地址，默认取第一个非回环 IPv4
+	Weight             float64           `toml:"weight"`              // 权重，写入 metadata.score 供选择器使用
+	Version            string            `toml:"version"`
+	Zone               string            `toml:"zone"`
+	Tags               map[string]string `toml:"tags"` // 自定义元数据
+
+	// transport
+	MaxRecvMsgSize        int           `toml:"max_recv_msg_size"`        // 最大接收消息字节数，默认 4MB
+	MaxSendMsgSize        int           `toml:"max_send_msg_size"`        // 最大发送消息字节数，默认不限制
+	MaxConcurrentStreams  uint32        `toml:"max_concurrent_streams"`   // 单连接最大并发流数
+	MaxConnectionIdle     time.Duration `toml:"max_connection_idle"`      // 连接空闲多久后关闭
+	MaxConnectionAge      time.Duration `toml:"max_connection_age"`       // 连接最长存活时间，便于客户端重新均衡
+	MaxConnectionAgeGrace time.Duration `toml:"max_connection_age_grace"` // 达到最长存活时间后等待在途 RPC 的时间
+	KeepaliveTime         time.Duration `toml:"keepalive_time"`           // 服务端空闲多久后 ping 客户端，默认 2h
+	KeepaliveTimeout      time.Duration `toml:"keepalive_timeout"`        // ping 超时时间，默认 20s
+	// keepalive enforcement
+	KeepaliveMinTime             time.Duration `toml:"keepalive_min_time"`              // 允许客户端 ping 的最小间隔，默认 5m
+	KeepalivePermitWithoutStream bool          `toml:"keepalive_permit_without_stream"` // 是否允许客户端在无活跃流时 ping
 
 	// log plugin
 	EnableLogPlugin   bool `toml:"enable_log_plugin"`
@@ -61,9 +96,42 @@ type GrpcConfig struct {
 	LogResponseResult bool `toml:"log_response_result"`
 	LogMaxLength      int  `toml:"log_max_length"`
 
-	// serialization
-	SerializeType string `toml:"serialize_type"` // 序列化类型: json, protobuf, msgpack, thrift
+	// tls
+	TLS *tlsx.Cfg `toml:"tls"`
+
+	// graceful stop
+	PropagationDelay time.Duration `toml:"propagation_delay"` // 注销后等待服务发现传播的时间，默认不等待
+	DrainTimeout     time.Duration `toml:"drain_timeout"`     // GracefulStop 的最长等待时间，超时后强制停止，默认 10s
+
+	// reflection
+	EnableReflection bool `toml:"enable_reflection"` // 注册 gRPC Server Reflection，便于 grpcurl 调试
 
+	// auth
+	Auth *auth.Cfg `toml:"auth"`
+
+	// limit
+	Limit *LimitCfg `toml:"limit"`
+}
+
+// warnDeprecated 对已废弃但仍配置了的字段输出警告
+func (c *GrpcConfig) warnDeprecated(name string) {
+	deprecated := []struct {
+		field string
+		set   bool
+	}{
+		{"read_timeout (use connection_timeout)", c.DialReadTimeout > 0},
+		{"write_timeout", c.DialWriteTimeout > 0},
+		{"register_zk_*", len(c.RegisterZkServers) > 0 || c.RegisterZkBasePath != "" || c.RegisterZkUpdateInterval > 0},
+		{"register_nc_* (use [rpc.server.<name>.nacos])", len(c.RegisterNcServers) > 0 || c.RegisterNcNamespaceId != "" ||
+			c.RegisterNcCacheDir != "" || c.RegisterNcLogDir != "" || c.RegisterNcLogLevel != "" || c.RegisterNcAccessKey != "" || c.RegisterNcSecretKey != ""},
+		{"etcd_endpoints/etcd_dial_timeout (use [etcd] and etcd_client)", len(c.EtcdEndpoints) > 0 || c.EtcdDialTimeout > 0},
+		{"serialize_type", c.SerializeType != ""},
+	}
+	for _, d := range deprecated {
+		if d.set {
+			xlog.Warnf(context.Background(), "rpc.server.%s: %s is deprecated and ignored", name, d.field)
+		}
+	}
 }
 
 func loadCfg(name string) (r *GrpcConfig, err error) {
@@ -78,7 +146,7 @@ func loadCfg(name string) (r *GrpcConfig, err error) {
 	return
 }
 
-func StartServer(name string, registerFunc func(*grpc.Server), interceptors []grpc.UnaryServerInterceptor) (err error) {
+func StartServer(name string, registerFunc func(*grpc.Server), interceptors []grpc.UnaryServerInterceptor, opts ...Option) (err error) {
 	lock.Lock()
 	defer lock.Unlock()
 	cfg, err := loadCfg(name)
@@ -86,7 +154,8 @@ func StartServer(name string, registerFunc func(*grpc.Server), interceptors []gr
 		return err
 	}
 
-	server, err := NewGrpcServer(cfg, name, registerFunc, interceptors...)
+	opts = append([]Option{WithUnaryInterceptors(interceptors...)}, opts...)
+	server, err := NewGrpcServerWithOptions(cfg, name, registerFunc, opts...)
 	if err != nil {
 		return err
 	}
@@ -98,10 +167,23 @@ func StartServer(name string, registerFunc func(*grpc.Server), interceptors []gr
 	return server.Start()
 }
 
+// UpdateServerMetadata 合并更新运行中服务的注册元数据，如预热期间调低权重
+func UpdateServerMetadata(name string, metadata map[string]string) error {
+	lock.RLock()
+	server, ok := servers[name]
+	lock.RUnlock()
+	if !ok {
+		return fmt.Errorf("server %s not found", name)
+	}
+	return server.UpdateMetadata(metadata)
+}
+
 func StopServer(name string) (err error) {
 	defer utils.Unlock()
 	lock.Lock()
 	server, ok := servers[name]
+	delete(servers, name)
+	lock.Unlock()
 	if !ok {
 		return fmt.Errorf("server %s not found", name)
 	}
@@ -112,6 +194,23 @@ func StopServer(name string) (err error) {
 func loadCfgs() (r map[string]*GrpcConfig, err error) {
 	r = map[string]*GrpcConfig{}
 
+	once.Do(func() {
+		config.Get(&rpcConfig{}, options.WithOpOnChangeFn(func(cfg interface{}) {
+			c, ok := cfg.(*rpcConfig)
+			if !ok || c.Rpc == nil {
+				return
+			}
+			lock.RLock()
+			defer lock.RUnlock()
+			// 运行中的服务按新配置更新限流
+			for name, server := range servers {
+				if sc := c.Rpc.Cfgs[name]; sc != nil {
+					server.updateCfg(sc)
+				}
+			}
+		}))
+	})
+
 	cfg := config.Get(&rpcConfig{}).(*rpcConfig)
 	if err == nil && (cfg.Rpc == nil || cfg.Rpc.Cfgs == nil || len(cfg.Rpc.Cfgs) == 0) {
 		err = fmt.Errorf("not configed")
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type nopRegister struct{}
+
+func (nopRegister) Register(string, string, map[string]string) error       { return nil }
+func (nopRegister) Unregister(string, string) error                        { return nil }
+func (nopRegister) UpdateMetadata(string, string, map[string]string) error { return nil }
+
+func TestStopForced(t *testing.T) {
+	s := &GrpcServer{
+		cfg:      &GrpcConfig{DrainTimeout: 100 * time.Millisecond},
+		register: nopRegister{},
+		health:   health.NewServer(),
+	}
+	started := make(chan struct{})
+	s.server = grpc.NewServer(
+		grpc.StreamInterceptor(s.inflightStreamInterceptor),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			close(started)
+			<-stream.Context().Done()
+			return stream.Context().Err()
+		}),
+	)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go conn.Invoke(context.Background(), "/test.Blocking/Wait", nil, nil)
+	<-started
+
+	var forced *ForcedStopError
+	if err := s.Stop(); !errors.As(err, &forced) || forced.Inflight != 1 {
+		t.Fatalf("expected forced stop with 1 rpc in flight, got %v", err)
+	}
+}
+
+func TestUnknownRegister(t *testing.T) {
+	_, err := NewGrpcServer(&GrpcConfig{DialAddr: "127.0.0.1:9000", AdvertiseHost: "127.0.0.1", Register: "zookeeper"}, "user", func(*grpc.Server) {})
+	if !errors.Is(err, ErrUnknownRegister) {
+		t.Fatalf("expected ErrUnknownRegister, got %v", err)
+	}
+}
+
+func TestStopEndsHealthWatch(t *testing.T) {
+	s := &GrpcServer{
+		cfg:      &GrpcConfig{DrainTimeout: 2 * time.Second},
+		register: nopRegister{},
+		health:   health.NewServer(),
+	}
+	s.server = grpc.NewServer(grpc.StreamInterceptor(s.inflightStreamInterceptor))
+	healthpb.RegisterHealthServer(s.server, s.health)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	watch, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = watch.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&s.inflight); n != 0 {
+		t.Fatalf("health watch should not count as in flight, got %d", n)
+	}
+
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatalf("expected graceful stop, got %v", err)
+	}
+	if d := time.Since(start); d >= s.cfg.DrainTimeout {
+		t.Fatalf("stop should not wait for the drain timeout, took %v", d)
+	}
+}
+
+// funcRegister 注册时执行指定函数
+type funcRegister struct {
+	nopRegister
+	register func() error
+}
+
+func (r funcRegister) Register(string, string, map[string]string) error { return r.register() }
+
+func TestStartRegistersBeforeServing(t *testing.T) {
+	newServer := func(register func(s *GrpcServer) error) *GrpcServer {
+		s := &GrpcServer{cfg: &GrpcConfig{DialAddr: "127.0.0.1:0"}, name: "user", health: health.NewServer()}
+		s.register = funcRegister{register: func() error { return register(s) }}
+		s.server = grpc.NewServer()
+		healthpb.RegisterHealthServer(s.server, s.health)
+		s.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		return s
+	}
+	servingStatus := func(s *GrpcServer) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := s.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+
+	// 注册期间仍为 NOT_SERVING，成功后置为 SERVING
+	s := newServer(func(s *GrpcServer) error {
+		if st := servingStatus(s); st != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("status during register = %v, want NOT_SERVING", st)
+		}
+		return nil
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if st := servingStatus(s); st != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("status after register = %v, want SERVING", st)
+	}
+	s.Stop()
+
+	// 注册失败时保持 NOT_SERVING 并停止服务
+	s = newServer(func(*GrpcServer) error { return errors.New("etcd unavailable") })
+	if err := s.Start(); err == nil {
+		t.Fatal("expected register error")
+	}
+	if st := servingStatus(s); st != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status after failed register = %v, want NOT_SERVING", st)
+	}
+	if conn, err := net.DialTimeout("tcp", s.listener.Addr().String(), time.Second); err == nil {
+		conn.Close()
+		t.Fatal("server should be stopped after failed register")
+	}
+}
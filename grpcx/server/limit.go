@@ -0,0 +1,253 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/liweiming-nova/common/auth"
+	"github.com/liweiming-nova/common/utils/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCallerKey  = "x-caller"
+	defaultMaxCallers = 10000
+)
+
+// LimitCfg 限流配置，对应 [rpc.server.<name>.limit]
+type LimitCfg struct {
+	Enable      bool                `toml:"enable"`
+	Rate        float64             `toml:"rate"`         // 全局每秒请求数，0 表示不限制
+	Burst       int                 `toml:"burst"`        // 全局突发容量，默认取 Rate
+	MaxInflight int64               `toml:"max_inflight"` // 最大并发处理数，0 表示不限制
+	Methods     map[string]*RateCfg `toml:"methods"`      // 完整方法名 -> 限流
+	CallerKey   string              `toml:"caller_key"`   // 未启用认证时识别调用方的 metadata key，默认 x-caller；启用认证时使用认证后的 subject
+	Callers     map[string]*RateCfg `toml:"callers"`      // 调用方 -> 限流，"*" 为未单独配置调用方的默认值（每个调用方独立计数）
+	MaxCallers  int                 `toml:"max_callers"`  // "*" 规则下最多跟踪的调用方数，超出时淘汰最久未访问的，默认 10000
+}
+
+// RateCfg 令牌桶配置
+type RateCfg struct {
+	Rate  float64 `toml:"rate"`
+	Burst int     `toml:"burst"`
+}
+
+// tokenBucket 令牌桶，rate <= 0 时不限制
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now func() time.Time) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b <= 0 {
+		b = rate
+	}
+	if b < 1 {
+		b = 1
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b, last: now(), now: now}
+}
+
+// ready 补充令牌并判断是否有可用令牌，不消耗令牌
+func (b *tokenBucket) ready() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	return b.tokens >= 1
+}
+
+// take 消耗一个令牌，并发时可能短暂透支，随时间补回
+func (b *tokenBucket) take() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.tokens--
+	b.mu.Unlock()
+}
+
+// limitState 某一版本配置对应的令牌桶，配置变化时整体替换
+type limitState struct {
+	cfg     *LimitCfg
+	now     func() time.Time
+	global  *tokenBucket
+	methods map[string]*tokenBucket
+
+	mu      sync.Mutex
+	callers map[string]*list.Element // caller -> *callerEntry，按访问时间排列，队尾最久未访问
+	lru     *list.List
+}
+
+type callerEntry struct {
+	caller string
+	bucket *tokenBucket
+}
+
+func newLimitState(cfg *LimitCfg, now func() time.Time) *limitState {
+	s := &limitState{cfg: cfg, now: now, methods: map[string]*tokenBucket{}, callers: map[string]*list.Element{}, lru: list.New()}
+	if cfg == nil || !cfg.Enable {
+		return s
+	}
+	s.global = newTokenBucket(cfg.Rate, cfg.Burst, now)
+	for method, c := range cfg.Methods {
+		s.methods[method] = newTokenBucket(c.Rate, c.Burst, now)
+	}
+	return s
+}
+
+func (s *limitState) callerBucket(caller string) *tokenBucket {
+	c := s.cfg.Callers[caller]
+	if c == nil {
+		if c = s.cfg.Callers["*"]; c == nil {
+			return nil
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.callers[caller]; ok {
+		s.lru.MoveToFront(e)
+		return e.Value.(*callerEntry).bucket
+	}
+	max := s.cfg.MaxCallers
+	if max <= 0 {
+		max = defaultMaxCallers
+	}
+	for s.lru.Len() >= max {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.callers, oldest.Value.(*callerEntry).caller)
+	}
+	b := newTokenBucket(c.Rate, c.Burst, s.now)
+	s.callers[caller] = s.lru.PushFront(&callerEntry{caller: caller, bucket: b})
+	return b
+}
+
+// caller 识别调用方：已认证时使用 Principal 的 subject，否则读取 caller_key 对应的 metadata（由客户端自报，不可信）
+func (s *limitState) caller(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Subject
+	}
+	key := s.cfg.CallerKey
+	if key == "" {
+		key = defaultCallerKey
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// Limiter 服务端限流拦截器：全局令牌桶、按方法、按调用方以及最大并发
+type Limiter struct {
+	state    atomic.Pointer[limitState]
+	inflight int64
+	now      func() time.Time
+
+	mu sync.Mutex // 串行化 Update
+}
+
+// NewLimiter 创建限流器
+func NewLimiter(cfg *LimitCfg) *Limiter {
+	return newLimiter(cfg, time.Now)
+}
+
+// newLimiter 使用指定时钟创建限流器，便于测试令牌补充
+func newLimiter(cfg *LimitCfg, now func() time.Time) *Limiter {
+	l := &Limiter{now: now}
+	l.Update(cfg)
+	return l
+}
+
+// Update 替换限流配置，配置有变化时令牌桶重新计数，并发计数保留；配置未变时保留现有令牌桶
+func (l *Limiter) Update(cfg *LimitCfg) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cur := l.state.Load(); cur != nil && reflect.DeepEqual(cur.cfg, cfg) {
+		return
+	}
+	l.state.Store(newLimitState(cfg, l.now))
+}
+
+// acquire 检查限流，通过时返回释放函数
+func (l *Limiter) acquire(ctx context.Context, method string) (func(), error) {
+	s := l.state.Load()
+	if s.cfg == nil || !s.cfg.Enable {
+		return func() {}, nil
+	}
+
+	// 先检查全部限制，都通过后再消耗令牌，被拒绝的请求不占用其他维度的额度
+	var caller *tokenBucket
+	if len(s.cfg.Callers) > 0 {
+		caller = s.callerBucket(s.caller(ctx))
+	}
+	reason := ""
+	if !s.global.ready() {
+		reason = "global"
+	} else if !s.methods[method].ready() {
+		reason = "method"
+	} else if !caller.ready() {
+		reason = "caller"
+	}
+	release := func() {}
+	if reason == "" && s.cfg.MaxInflight > 0 {
+		if atomic.AddInt64(&l.inflight, 1) > s.cfg.MaxInflight {
+			atomic.AddInt64(&l.inflight, -1)
+			reason = "inflight"
+		} else {
+			release = func() { atomic.AddInt64(&l.inflight, -1) }
+		}
+	}
+	if reason != "" {
+		metrics.GetCounter("grpc_server_rejected_total", "method", method, "reason", reason).Inc()
+		return nil, status.Errorf(codes.ResourceExhausted, "%s rate limit exceeded", reason)
+	}
+	s.global.take()
+	s.methods[method].take()
+	caller.take()
+	return release, nil
+}
+
+// UnaryServerInterceptor 实现 gRPC Unary 服务端限流
+func (l *Limiter) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	release, err := l.acquire(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor 实现 gRPC Stream 服务端限流，流存续期间占用并发数
+func (l *Limiter) StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	release, err := l.acquire(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return handler(srv, ss)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liweiming-nova/common/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock 手动推进的时钟，用于控制令牌补充
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestLimiter(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(&LimitCfg{
+		Enable:      true,
+		MaxInflight: 2,
+		Methods:     map[string]*RateCfg{"/user.UserServer/Get": {Rate: 1, Burst: 1}},
+		Callers:     map[string]*RateCfg{"*": {Rate: 1, Burst: 2}},
+	}, clock.Now)
+	ctx := func(caller string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(defaultCallerKey, caller))
+	}
+
+	release, err := l.acquire(ctx("a"), "/user.UserServer/Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err = l.acquire(ctx("a"), "/user.UserServer/Get"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected method limit, got %v", err)
+	}
+	// 令牌按时间补充
+	clock.Advance(time.Second)
+	release, err = l.acquire(ctx("a"), "/user.UserServer/Get")
+	if err != nil {
+		t.Fatalf("expected token refilled, got %v", err)
+	}
+	release()
+
+	// 每个调用方独立计数
+	release, err = l.acquire(ctx("b"), "/user.UserServer/List")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.acquire(ctx("c"), "/user.UserServer/List"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = l.acquire(ctx("d"), "/user.UserServer/List"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected inflight limit, got %v", err)
+	}
+	release()
+	if _, err = l.acquire(ctx("d"), "/user.UserServer/List"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Update(&LimitCfg{Enable: false})
+	if _, err = l.acquire(ctx("a"), "/user.UserServer/Get"); err != nil {
+		t.Fatalf("expected no limit after update, got %v", err)
+	}
+}
+
+func TestLimiterCallers(t *testing.T) {
+	clock := newFakeClock()
+	l := newLimiter(&LimitCfg{
+		Enable:     true,
+		Rate:       1,
+		Burst:      1,
+		Callers:    map[string]*RateCfg{"blocked": {Rate: 0.001, Burst: 1}, "*": {Rate: 100}},
+		MaxCallers: 2,
+	}, clock.Now)
+	blocked := metadata.NewIncomingContext(context.Background(), metadata.Pairs(defaultCallerKey, "blocked"))
+	release, err := l.acquire(blocked, "/user.UserServer/Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	// 一秒后全局令牌补回，blocked 调用方的令牌仍未补回
+	clock.Advance(time.Second)
+
+	// 调用方限流拒绝时不消耗全局令牌
+	if _, err = l.acquire(blocked, "/user.UserServer/Get"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected caller limit, got %v", err)
+	}
+	// 已认证时按 subject 计数，伪造 x-caller 无法绕过
+	spoofed := auth.NewContext(blocked, &auth.Principal{Subject: "order_server"})
+	if _, err = l.acquire(spoofed, "/user.UserServer/Get"); err != nil {
+		t.Fatalf("global token should be kept after caller rejection, got %v", err)
+	}
+
+	// 跟踪的调用方数量受 max_callers 限制
+	s := l.state.Load()
+	for _, caller := range []string{"a", "b", "c", "d"} {
+		s.callerBucket(caller)
+	}
+	if n := len(s.callers); n != 2 {
+		t.Fatalf("expected 2 tracked callers, got %d", n)
+	}
+	if _, ok := s.callers["d"]; !ok {
+		t.Fatal("most recent caller should be tracked")
+	}
+}
+
+func TestLimiterUpdate(t *testing.T) {
+	cfg := func(rate float64) *LimitCfg {
+		return &LimitCfg{Enable: true, Methods: map[string]*RateCfg{"/user.UserServer/Get": {Rate: rate, Burst: 1}}}
+	}
+	l := newLimiter(cfg(1), newFakeClock().Now)
+	if _, err := l.acquire(context.Background(), "/user.UserServer/Get"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置未变时保留令牌桶，已消耗的令牌不会因热更新而重置
+	l.Update(cfg(1))
+	if _, err := l.acquire(context.Background(), "/user.UserServer/Get"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected method limit after unchanged update, got %v", err)
+	}
+
+	// 配置变化时重建令牌桶
+	l.Update(cfg(2))
+	if _, err := l.acquire(context.Background(), "/user.UserServer/Get"); err != nil {
+		t.Fatalf("expected fresh bucket after changed update, got %v", err)
+	}
+}
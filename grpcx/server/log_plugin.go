@@ -3,69 +3,35 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/liweiming-nova/common/grpcx/logging"
 	"github.com/liweiming-nova/common/xlog"
 	"google.golang.org/grpc"
 )
 
 // LogInterceptor 是一个 gRPC 拦截器，用于记录请求和响应的日志
 type LogInterceptor struct {
-	EnableRequestLog  bool
-	EnableResponseLog bool
-	EnableErrorLog    bool
-	LogRequestArgs    bool
-	LogResponseResult bool
-	MaxLogLength      int
+	logging.Options
 }
 
-// NewLogInterceptor 创建一个新的日志插件（配置方式不变）
+// NewLogInterceptor 创建一个新的日志插件，选项与客户端日志拦截器共用
 func NewLogInterceptor(options ...LogInterceptorOption) *LogInterceptor {
-	plugin := &LogInterceptor{
-		EnableRequestLog:  true,
-		EnableResponseLog: true,
-		EnableErrorLog:    true,
-		LogRequestArgs:    true,
-		LogResponseResult: true,
-		MaxLogLength:      1024,
-	}
-
-	for _, option := range options {
-		option(plugin)
-	}
-
-	return plugin
+	return &LogInterceptor{Options: logging.NewOptions(options...)}
 }
 
-// LogInterceptorOption 保持不变
-type LogInterceptorOption func(*LogInterceptor)
-
-func WithEnableRequestLog(enable bool) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.EnableRequestLog = enable }
-}
-
-func WithEnableResponseLog(enable bool) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.EnableResponseLog = enable }
-}
+type LogInterceptorOption = logging.Option
 
-func WithEnableErrorLog(enable bool) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.EnableErrorLog = enable }
-}
-
-func WithLogRequestArgs(enable bool) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.LogRequestArgs = enable }
-}
-
-func WithLogResponseResult(enable bool) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.LogResponseResult = enable }
-}
-
-func WithMaxLogLength(length int) LogInterceptorOption {
-	return func(p *LogInterceptor) { p.MaxLogLength = length }
-}
+var (
+	WithEnableRequestLog  = logging.WithEnableRequestLog
+	WithEnableResponseLog = logging.WithEnableResponseLog
+	WithEnableErrorLog    = logging.WithEnableErrorLog
+	WithLogRequestArgs    = logging.WithLogRequestArgs
+	WithLogResponseResult = logging.WithLogResponseResult
+	WithMaxLogLength      = logging.WithMaxLogLength
+)
 
 // UnaryServerInterceptor 实现 gRPC Unary 拦截器
 func (p *LogInterceptor) UnaryServerInterceptor(
@@ -83,7 +49,7 @@ func (p *LogInterceptor) UnaryServerInterceptor(
 	if p.EnableRequestLog {
 		logMsg := fmt.Sprintf("gRPC Request - Service: %s, Method: %s", serviceName, methodName)
 		if p.LogRequestArgs && req != nil {
-			argsStr := p.safeMarshal(req)
+			argsStr := p.Marshal(req)
 			logMsg += fmt.Sprintf(", Args: %s", argsStr)
 		}
 		xlog.Infof(ctx, logMsg)
@@ -102,7 +68,7 @@ func (p *LogInterceptor) UnaryServerInterceptor(
 		}
 	} else if p.EnableResponseLog {
 		if p.LogResponseResult && resp != nil {
-			resultStr := p.safeMarshal(resp)
+			resultStr := p.Marshal(resp)
 			logMsg += fmt.Sprintf(", Result: %s", resultStr)
 		}
 		xlog.Infof(ctx, logMsg)
@@ -111,6 +77,68 @@ func (p *LogInterceptor) UnaryServerInterceptor(
 	return resp, err
 }
 
+// StreamServerInterceptor 实现 gRPC Stream 拦截器，记录收发消息数与耗时
+func (p *LogInterceptor) StreamServerInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+
+	startTime := time.Now()
+	ctx := ss.Context()
+
+	serviceName, methodName := parseFullMethod(info.FullMethod)
+
+	// 记录请求日志
+	if p.EnableRequestLog {
+		xlog.Infof(ctx, "gRPC Stream Start - Service: %s, Method: %s, ClientStream: %v, ServerStream: %v",
+			serviceName, methodName, info.IsClientStream, info.IsServerStream)
+	}
+
+	// 调用实际 handler
+	wrapped := &countingServerStream{ServerStream: ss}
+	err := handler(srv, wrapped)
+
+	// 记录响应日志
+	duration := time.Since(startTime)
+	logMsg := fmt.Sprintf("gRPC Stream End - Service: %s, Method: %s, Duration: %v, Recv: %d, Sent: %d",
+		serviceName, methodName, duration, wrapped.recv, wrapped.sent)
+
+	if err != nil {
+		if p.EnableErrorLog {
+			xlog.Errorf(ctx, "%s, Error: %v", logMsg, err)
+		}
+	} else if p.EnableResponseLog {
+		xlog.Info(ctx, logMsg)
+	}
+
+	return err
+}
+
+// countingServerStream 统计流上成功收发的消息数
+type countingServerStream struct {
+	grpc.ServerStream
+	recv int64
+	sent int64
+}
+
+func (s *countingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.recv++
+	}
+	return err
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
 // parseFullMethod 从 /package.Service/Method 解析出 Service 和 Method
 func parseFullMethod(fullMethod string) (service, method string) {
 	parts := strings.Split(fullMethod, "/")
@@ -126,25 +154,3 @@ func parseFullMethod(fullMethod string) (service, method string) {
 	}
 	return service, method
 }
-
-// safeMarshal 安全地序列化对象为JSON字符串（保持不变）
-func (p *LogInterceptor) safeMarshal(v interface{}) string {
-	if v == nil {
-		return ""
-	}
-
-	data, err := json.Marshal(v)
-	if err != nil {
-		return p.truncateString(fmt.Sprintf("%+v", v))
-	}
-
-	return p.truncateString(string(data))
-}
-
-// truncateString 截断字符串（保持不变）
-func (p *LogInterceptor) truncateString(s string) string {
-	if len(s) <= p.MaxLogLength {
-		return s
-	}
-	return s[:p.MaxLogLength] + "..."
-}
@@ -0,0 +1,33 @@
+package server
+
+import "google.golang.org/grpc"
+
+// Options NewGrpcServerWithOptions 的可选项
+type Options struct {
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	serverOptions      []grpc.ServerOption
+}
+
+type Option func(o *Options)
+
+// WithUnaryInterceptors 追加 unary 拦截器
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *Options) {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors 追加 stream 拦截器
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *Options) {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+	}
+}
+
+// WithServerOptions 追加原生 grpc.ServerOption
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *Options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerIdentity mTLS 下已校验的客户端身份
+type PeerIdentity struct {
+	CommonName   string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+	Certificate  *x509.Certificate
+}
+
+// PeerIdentityFromContext 在 handler 中获取已通过校验的客户端证书身份，非 mTLS 连接返回 false
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil, false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	identity := &PeerIdentity{
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.String(),
+		Certificate:  cert,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity, true
+}
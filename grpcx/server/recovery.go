@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/liweiming-nova/common/utils/metrics"
+	"github.com/liweiming-nova/common/xlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryUnaryServerInterceptor 捕获 handler 中的 panic，记录堆栈并返回 codes.Internal
+func RecoveryUnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// RecoveryStreamServerInterceptor 捕获 stream handler 中的 panic，记录堆栈并返回 codes.Internal
+func RecoveryStreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ss.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func recovered(ctx context.Context, method string, r interface{}) error {
+	metrics.GetCounter("grpc_server_panics_total", "method", method).Inc()
+	xlog.Errorf(ctx, "gRPC Server panic - Method: %s, TraceId: %s, Panic: %v\n%s", method, incomingTraceID(ctx), r, debug.Stack())
+	return status.Errorf(codes.Internal, "internal error")
+}
+
+func incomingTraceID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(xlog.TraceId); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/liweiming-nova/common/utils/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// panicService 按请求决定是否 panic 的测试服务
+var panicService = grpc.ServiceDesc{
+	ServiceName: "test.Panic",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Call",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(emptypb.Empty)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				panic("boom")
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Panic/Call"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			panic("boom")
+		},
+	}},
+}
+
+func TestRecovery(t *testing.T) {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(RecoveryUnaryServerInterceptor),
+		grpc.ChainStreamInterceptor(RecoveryStreamServerInterceptor),
+	)
+	s.RegisterService(&panicService, struct{}{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	panics := metrics.GetCounter("grpc_server_panics_total", "method", "/test.Panic/Call")
+	before := panics.Value()
+	// 连续调用均返回 Internal，说明 panic 后服务仍在运行
+	for i := 0; i < 2; i++ {
+		err = conn.Invoke(context.Background(), "/test.Panic/Call", &emptypb.Empty{}, &emptypb.Empty{})
+		if status.Code(err) != codes.Internal {
+			t.Fatalf("call %d: expected Internal, got %v", i, err)
+		}
+	}
+	if n := panics.Value() - before; n != 2 {
+		t.Fatalf("expected 2 panics recorded, got %d", n)
+	}
+
+	stream, err := conn.NewStream(context.Background(), &panicService.Streams[0], "/test.Panic/Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.RecvMsg(&emptypb.Empty{}); status.Code(err) != codes.Internal {
+		t.Fatalf("stream: expected Internal, got %v", err)
+	}
+}
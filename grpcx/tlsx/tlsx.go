@@ -0,0 +1,217 @@
+package tlsx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// Cfg TLS 配置，对应 [rpc.server.<name>.tls] 与 [rpc.client.<name>.tls]
+type Cfg struct {
+	Enable             bool          `toml:"enable"`
+	CertFile           string        `toml:"cert_file"`            // 服务端证书，或 mTLS 下的客户端证书
+	KeyFile            string        `toml:"key_file"`             // 证书私钥
+	CAFile             string        `toml:"ca_file"`              // 服务端用于校验客户端证书，客户端用于校验服务端证书
+	ClientAuth         bool          `toml:"client_auth"`          // 服务端是否要求并校验客户端证书（mTLS）
+	ServerName         string        `toml:"server_name"`          // 客户端校验服务端证书时使用的名称，默认取连接地址
+	InsecureSkipVerify bool          `toml:"insecure_skip_verify"` // 客户端跳过服务端证书校验，仅用于测试
+	ReloadInterval     time.Duration `toml:"reload_interval"`      // 检查证书文件变化的间隔，默认 30s
+}
+
+// Enabled 判断是否启用 TLS
+func (c *Cfg) Enabled() bool {
+	return c != nil && c.Enable
+}
+
+// Reloader 定期检查证书文件的修改时间，变化后重新加载证书与 CA
+type Reloader struct {
+	cfg *Cfg
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	modTime time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReloader 加载证书并启动后台检查
+func NewReloader(cfg *Cfg) (*Reloader, error) {
+	r := &Reloader{cfg: cfg, done: make(chan struct{})}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go r.watch(interval)
+	return r, nil
+}
+
+func (r *Reloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.load(); err != nil {
+				xlog.Errorf(context.Background(), "TLS reload %s error: %v", r.cfg.CertFile, err)
+				continue
+			}
+			xlog.Infof(context.Background(), "TLS certificates reloaded: cert=%s ca=%s", r.cfg.CertFile, r.cfg.CAFile)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// latestModTime 返回证书、私钥与 CA 文件中最新的修改时间
+func (r *Reloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if file == "" {
+			continue
+		}
+		if fi, err := os.Stat(file); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.latestModTime().After(r.modTime)
+}
+
+func (r *Reloader) load() error {
+	modTime := r.latestModTime()
+
+	var cert *tls.Certificate
+	if r.cfg.CertFile != "" || r.cfg.KeyFile != "" {
+		if r.cfg.CertFile == "" || r.cfg.KeyFile == "" {
+			return fmt.Errorf("TLS requires cert_file and key_file to be set together")
+		}
+		c, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		cert = &c
+	}
+
+	var caPool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		caCert, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("failed to append CA certificate")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.caPool, r.modTime = cert, caPool, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// Certificate 返回当前证书
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// CAPool 返回当前 CA，未配置时为 nil
+func (r *Reloader) CAPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.caPool
+}
+
+// Close 停止后台检查
+func (r *Reloader) Close() {
+	r.closeOnce.Do(func() { close(r.done) })
+}
+
+// ServerConfig 构建服务端 tls.Config，每次握手使用最新的证书与 CA
+func (r *Reloader) ServerConfig() (*tls.Config, error) {
+	if r.Certificate() == nil {
+		return nil, errors.New("TLS server requires cert_file and key_file")
+	}
+	if r.cfg.ClientAuth && r.CAPool() == nil {
+		return nil, errors.New("TLS client_auth requires ca_file")
+	}
+
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.GetConfigForClient = nil
+		c.Certificates = []tls.Certificate{*r.Certificate()}
+		if caPool := r.CAPool(); caPool != nil {
+			c.ClientCAs = caPool
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+			if r.cfg.ClientAuth {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		return c, nil
+	}
+	return base, nil
+}
+
+// ClientConfig 构建客户端 tls.Config，客户端证书与 CA 均支持热更新
+func (r *Reloader) ClientConfig() *tls.Config {
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.cfg.ServerName,
+	}
+	if r.Certificate() != nil {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		}
+	}
+	if r.cfg.InsecureSkipVerify {
+		c.InsecureSkipVerify = true
+		return c
+	}
+	if r.CAPool() == nil {
+		// 未配置 CA 时使用系统根证书校验
+		return c
+	}
+
+	// 自定义校验以便使用热更新后的 CA：关闭内置校验，在 VerifyConnection 中按当前 CA 校验
+	c.InsecureSkipVerify = true
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: server presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         r.CAPool(),
+			DNSName:       cs.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package tlsx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert 签发证书并写入 dir/<name>.pem、dir/<name>.key，parent 为空时自签为 CA
+func writeCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	_ = os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	_ = os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	writeCert(t, dir, "user_server", ca, caKey)
+	writeCert(t, dir, "order_server", ca, caKey)
+
+	server, err := NewReloader(&Cfg{Enable: true, ClientAuth: true,
+		CertFile: filepath.Join(dir, "user_server.pem"), KeyFile: filepath.Join(dir, "user_server.key"), CAFile: filepath.Join(dir, "ca.pem")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, err := NewReloader(&Cfg{Enable: true, ServerName: "user_server",
+		CertFile: filepath.Join(dir, "order_server.pem"), KeyFile: filepath.Join(dir, "order_server.key"), CAFile: filepath.Join(dir, "ca.pem")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	serverConfig, err := server.ServerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c1, c2 := net.Pipe()
+	errCh := make(chan error, 1)
+	var peerName string
+	go func() {
+		conn := tls.Server(c2, serverConfig)
+		err := conn.Handshake()
+		if err == nil && len(conn.ConnectionState().VerifiedChains) > 0 {
+			peerName = conn.ConnectionState().VerifiedChains[0][0].Subject.CommonName
+		}
+		errCh <- err
+	}()
+	conn := tls.Client(c1, client.ClientConfig())
+	if err := conn.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if peerName != "order_server" {
+		t.Fatalf("unexpected peer identity %q", peerName)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/liweiming-nova/common/utils/metrics"
+	"github.com/liweiming-nova/common/xlog"
+)
+
+// Recovery 捕获 handler 中的 panic，记录堆栈并返回 500
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				traceID := r.Header.Get(xlog.TraceId)
+				if traceID == "" {
+					traceID = r.Header.Get("X-Trace-Id")
+				}
+				// 路径由客户端决定，不作为指标标签，避免标签基数无限增长
+				metrics.GetCounter("http_server_panics_total").Inc()
+				xlog.Errorf(r.Context(), "HTTP Server panic - %s %s, TraceId: %s, Panic: %v\n%s", r.Method, r.URL.Path, traceID, rec, debug.Stack())
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liweiming-nova/common/utils/metrics"
+)
+
+func TestRecovery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic/", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	srv := httptest.NewServer(Recovery(mux))
+	defer srv.Close()
+
+	panics := metrics.GetCounter("http_server_panics_total")
+	before := panics.Value()
+	for _, path := range []string{"/panic/1", "/panic/2"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("%s: expected 500, got %d", path, resp.StatusCode)
+		}
+	}
+	if n := panics.Value() - before; n != 2 {
+		t.Fatalf("expected 2 panics recorded under one series, got %d", n)
+	}
+
+	// panic 之后服务仍可正常处理请求
+	resp, err := http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 after panics, got %d", resp.StatusCode)
+	}
+}
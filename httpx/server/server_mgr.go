@@ -10,6 +10,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"github.com/liweiming-nova/common/auth"
 	"github.com/liweiming-nova/common/config"
 	"github.com/liweiming-nova/common/utils"
 	"net/http"
@@ -39,6 +40,9 @@ type Cfg struct {
 	DialReadTimeout  time.Duration `toml:"read_timeout"`
 	DialWriteTimeout time.Duration `toml:"write_timeout"`
 	DialIdleTimeout  time.Duration `toml:"idle_timeout"`
+
+	// auth
+	Auth *auth.Cfg `toml:"auth"`
 }
 
 func StartDefaultServer(rcvr http.Handler) (err error) {
@@ -56,9 +60,21 @@ func DefaultServer() (r *http.Server) {
 func StartServe(name string, rcvr http.Handler) (err error) {
 	utils.Lock(1)
 	var srv *http.Server
-	if srv, err = SafeServer(name); err == nil {
-		srv.Handler = rcvr
+	if srv, err = SafeServer(name); err != nil {
+		return
+	}
+	var cfg *Cfg
+	if cfg, err = loadCfg(name); err != nil {
+		return
+	}
+	if cfg.Auth.Enabled() {
+		var authenticator *auth.Authenticator
+		if authenticator, err = auth.New(cfg.Auth); err != nil {
+			return
+		}
+		rcvr = authenticator.Middleware(rcvr)
 	}
+	srv.Handler = Recovery(rcvr)
 	return
 }
 
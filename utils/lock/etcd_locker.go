@@ -0,0 +1,128 @@
+package lock
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liweiming-nova/common/xlog"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const defaultEtcdLockPrefix = "/locks/"
+
+// EtcdLocker 基于 etcd concurrency.Mutex 的分布式锁
+//
+// 每次 Acquire 创建独立的 session（租约），持锁期间自动续约，
+// ttl 决定进程异常退出后锁多久自动释放，而不是持锁的最长时间；
+// 同一进程内多个 goroutine 竞争同一 key 时同样互斥
+type EtcdLocker struct {
+	client *clientv3.Client
+	prefix string
+
+	mu   sync.Mutex
+	held map[string]*etcdLock // token -> lock
+}
+
+type etcdLock struct {
+	key     string
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+// EtcdLockerOption EtcdLocker 选项
+type EtcdLockerOption func(l *EtcdLocker)
+
+// WithEtcdLockPrefix 指定锁 key 前缀，默认 /locks/
+func WithEtcdLockPrefix(prefix string) EtcdLockerOption {
+	return func(l *EtcdLocker) {
+		if prefix != "" {
+			l.prefix = prefix
+		}
+	}
+}
+
+func NewEtcdLocker(c *clientv3.Client, opts ...EtcdLockerOption) *EtcdLocker {
+	l := &EtcdLocker{client: c, prefix: defaultEtcdLockPrefix, held: map[string]*etcdLock{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Acquire 阻塞直到获得锁或 ctx 结束，返回用于 Release 的 token
+func (l *EtcdLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if key == "" || strings.Contains(key, "\x00") {
+		return "", ErrLockInvalidKey
+	}
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	// 租约在 ctx 下申请，session 本身不随 ctx 结束，直到 Release
+	lease, err := l.client.Grant(ctx, seconds)
+	if err != nil {
+		return "", l.acquireError(ctx, key, err)
+	}
+	session, err := concurrency.NewSession(l.client, concurrency.WithLease(lease.ID))
+	if err != nil {
+		l.revoke(lease.ID)
+		return "", l.acquireError(ctx, key, err)
+	}
+	mutex := concurrency.NewMutex(session, l.prefix+key)
+	if err = mutex.Lock(ctx); err != nil {
+		session.Close()
+		return "", l.acquireError(ctx, key, err)
+	}
+
+	token := genLockToken()
+	l.mu.Lock()
+	l.held[token] = &etcdLock{key: key, session: session, mutex: mutex}
+	l.mu.Unlock()
+	return token, nil
+}
+
+func (l *EtcdLocker) acquireError(ctx context.Context, key string, err error) error {
+	if ctx.Err() != nil {
+		xlog.Errorf(ctx, "lock acquire timeout key:%s", key)
+		return ErrLockTimeout
+	}
+	xlog.Errorf(ctx, "lock acquire failed key:%s, err:%v", key, err)
+	return ErrLockNetwork
+}
+
+func (l *EtcdLocker) revoke(id clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	l.client.Revoke(ctx, id)
+}
+
+// Release 释放锁；token 不匹配或租约已丢失（锁可能已被他人持有）时返回 ErrReleaseFailed
+func (l *EtcdLocker) Release(key, token string) (bool, error) {
+	l.mu.Lock()
+	held, ok := l.held[token]
+	if ok && held.key == key {
+		delete(l.held, token)
+	}
+	l.mu.Unlock()
+	if !ok || held.key != key {
+		return false, ErrReleaseFailed
+	}
+	defer held.session.Close()
+
+	select {
+	case <-held.session.Done():
+		return false, ErrReleaseFailed
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := held.mutex.Unlock(ctx); err != nil {
+		return false, ErrLockNetwork
+	}
+	return true, nil
+}
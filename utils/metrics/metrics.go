@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter 单调递增计数器
+type Counter struct {
+	v int64
+}
+
+func (c *Counter) Inc()          { atomic.AddInt64(&c.v, 1) }
+func (c *Counter) Add(n int64)   { atomic.AddInt64(&c.v, n) }
+func (c *Counter) Value() int64  { return atomic.LoadInt64(&c.v) }
+func (c *Counter) write() string { return fmt.Sprintf("%d", c.Value()) }
+
+// Gauge 可增可减的瞬时值
+type Gauge struct {
+	v int64
+}
+
+func (g *Gauge) Inc()          { atomic.AddInt64(&g.v, 1) }
+func (g *Gauge) Dec()          { atomic.AddInt64(&g.v, -1) }
+func (g *Gauge) Set(n int64)   { atomic.StoreInt64(&g.v, n) }
+func (g *Gauge) Value() int64  { return atomic.LoadInt64(&g.v) }
+func (g *Gauge) write() string { return fmt.Sprintf("%d", g.Value()) }
+
+// Summary 记录耗时的次数、总和与最大值
+type Summary struct {
+	mu    sync.Mutex
+	count int64
+	sum   float64
+	max   float64
+}
+
+// Observe 记录一次耗时
+func (s *Summary) Observe(d time.Duration) {
+	v := d.Seconds()
+	s.mu.Lock()
+	s.count++
+	s.sum += v
+	s.max = math.Max(s.max, v)
+	s.mu.Unlock()
+}
+
+// Value 返回次数、总和（秒）与最大值（秒）
+func (s *Summary) Value() (count int64, sum float64, max float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count, s.sum, s.max
+}
+
+type metric interface{}
+
+var (
+	lock    sync.RWMutex
+	metrics = map[string]metric{}
+)
+
+// key 由指标名与标签组成，如 name{k1="v1",k2="v2"}，labels 为 k1, v1, k2, v2 交替排列
+func key(name string, labels []string) string {
+	if len(labels) < 2 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func get(k string, create func() metric) metric {
+	lock.RLock()
+	m, ok := metrics[k]
+	lock.RUnlock()
+	if ok {
+		return m
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if m, ok = metrics[k]; !ok {
+		m = create()
+		metrics[k] = m
+	}
+	return m
+}
+
+// GetCounter 获取（不存在则创建）计数器
+func GetCounter(name string, labels ...string) *Counter {
+	return get(key(name, labels), func() metric { return &Counter{} }).(*Counter)
+}
+
+// GetGauge 获取（不存在则创建）瞬时值
+func GetGauge(name string, labels ...string) *Gauge {
+	return get(key(name, labels), func() metric { return &Gauge{} }).(*Gauge)
+}
+
+// GetSummary 获取（不存在则创建）耗时统计
+func GetSummary(name string, labels ...string) *Summary {
+	return get(key(name, labels), func() metric { return &Summary{} }).(*Summary)
+}
+
+// Write 以文本格式输出全部指标（兼容 Prometheus 文本格式）
+func Write(w io.Writer) {
+	lock.RLock()
+	keys := make([]string, 0, len(metrics))
+	for k := range metrics {
+		keys = append(keys, k)
+	}
+	lock.RUnlock()
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		lock.RLock()
+		m := metrics[k]
+		lock.RUnlock()
+		switch v := m.(type) {
+		case *Counter:
+			fmt.Fprintf(w, "%s %s\n", k, v.write())
+		case *Gauge:
+			fmt.Fprintf(w, "%s %s\n", k, v.write())
+		case *Summary:
+			count, sum, max := v.Value()
+			name, labels := k, ""
+			if i := strings.Index(k, "{"); i >= 0 {
+				name, labels = k[:i], k[i:]
+			}
+			fmt.Fprintf(w, "%s_count%s %d\n", name, labels, count)
+			fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, sum)
+			fmt.Fprintf(w, "%s_max%s %g\n", name, labels, max)
+		}
+	}
+}
+
+// Handler 返回输出全部指标的 http.Handler，可挂载到 /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w)
+	})
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "net"
+import (
+	"fmt"
+	"net"
+)
 
 func GetLocalIP() (r string, err error) {
 	var addrs []net.Addr
@@ -18,3 +21,22 @@ func GetLocalIP() (r string, err error) {
 	}
 	return
 }
+
+// GetInterfaceIP 返回指定网卡的第一个 IPv4 地址
+func GetInterfaceIP(name string) (r string, err error) {
+	var iface *net.Interface
+	if iface, err = net.InterfaceByName(name); err != nil {
+		return
+	}
+	var addrs []net.Addr
+	if addrs, err = iface.Addrs(); err != nil {
+		return
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.String(), nil
+		}
+	}
+	err = fmt.Errorf("no ipv4 address on interface %s", name)
+	return
+}